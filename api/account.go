@@ -1,34 +1,43 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
 
+	"github.com/NoahFola/simple_bank/apperr"
 	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
 	"github.com/gin-gonic/gin"
 )
 
+var errAccountNotOwned = apperr.New(apperr.Forbidden, "account doesn't belong to the authenticated user")
+
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
-	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
+	Currency string `json:"currency" binding:"required,currency"`
 }
 
 func (s *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.Error(validationError(err))
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.CreateAccountParams{
-		Owner:    req.Owner,
+		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
 	}
 
 	account, err := s.store.CreateAccount(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		// The owner comes from the token, so it can only be missing if the
+		// user was deleted after logging in.
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			ctx.Error(apperr.Wrap(err, apperr.Forbidden, "user no longer exists"))
+			return
+		}
+		ctx.Error(err)
 		return
 	}
 
@@ -42,18 +51,12 @@ type getAccountByIDRequest struct {
 func (s *Server) getAccountByID(ctx *gin.Context) {
 	var req getAccountByIDRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.Error(validationError(err))
 		return
 	}
 
-	account, err := s.store.GetAccount(ctx, req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	account, ok := s.ownedAccount(ctx, req.ID)
+	if !ok {
 		return
 	}
 
@@ -61,28 +64,51 @@ func (s *Server) getAccountByID(ctx *gin.Context) {
 }
 
 type ListAccountsRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	pageRequest
 }
 
 func (s *Server) getAllAccounts(ctx *gin.Context) {
 	var req ListAccountsRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.Error(validationError(err))
 		return
 	}
 
-	arg := db.ListAccountsParams{
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.usesOffset(ctx) {
+		arg := db.ListAccountsByOwnerParams{
+			Owner:  authPayload.Username,
+			Limit:  req.PageSize,
+			Offset: req.offset(),
+		}
+		accounts, err := s.store.ListAccountsByOwner(ctx, arg)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, accounts)
+		return
 	}
-	account, err := s.store.ListAccounts(ctx, arg)
+
+	afterID, err := req.afterID()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.Error(validationError(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	arg := db.ListAccountsByOwnerAfterParams{
+		Owner:   authPayload.Username,
+		AfterID: afterID,
+		Limit:   req.limit(),
+	}
+	accounts, err := s.store.ListAccountsByOwnerAfter(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(accounts, req.PageSize, func(a db.Account) int64 { return a.ID }))
 }
 
 type updateAccountByIDRequest struct {
@@ -90,35 +116,105 @@ type updateAccountByIDRequest struct {
 }
 
 type updateAccountBalanceRequest struct {
-	Balance int64 `json:"balance" binding:"required"`
+	// a pointer so that a balance of 0 still counts as given
+	Balance *int64 `json:"balance" binding:"required"`
 }
 
+// updateAccount lets an admin set a balance outright. The difference is
+// recorded as an entry so the ledger stays consistent with the balance.
 func (s *Server) updateAccount(ctx *gin.Context) {
 	var balanceReq updateAccountBalanceRequest
 	var idReq updateAccountByIDRequest
 
 	if err := ctx.ShouldBindJSON(&balanceReq); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.Error(validationError(err))
 		return
 	}
 	if err := ctx.ShouldBindUri(&idReq); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.Error(validationError(err))
 		return
 	}
 
-	args := db.UpdateAccountParams{
-		ID:      idReq.ID,
-		Balance: balanceReq.Balance,
+	args := db.AdjustBalanceTxParams{
+		AccountID: idReq.ID,
+		Balance:   *balanceReq.Balance,
 	}
 
-	account, err := s.store.UpdateAccount(ctx, args)
+	result, err := s.store.AdjustBalanceTx(ctx, args)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, result)
+}
+
+type accountBalanceURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type accountBalanceRequest struct {
+	Amount int64 `json:"amount" binding:"required,gt=0"`
+}
+
+// createDeposit credits money from outside the bank, so only an admin may
+// make one. It can go to any account.
+func (s *Server) createDeposit(ctx *gin.Context) {
+	var uri accountBalanceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req accountBalanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.getAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	result, err := s.store.DepositTx(ctx, db.DepositTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (s *Server) createWithdrawal(ctx *gin.Context) {
+	var uri accountBalanceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req accountBalanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	result, err := s.store.WithdrawTx(ctx, db.WithdrawTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
+	ctx.JSON(http.StatusOK, result)
 }
 
 type deleteAccountRequest struct {
@@ -128,19 +224,37 @@ type deleteAccountRequest struct {
 func (s *Server) deleteAccount(ctx *gin.Context) {
 	var req deleteAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.ownedAccount(ctx, req.ID); !ok {
 		return
 	}
 
 	err := s.store.DeleteAccount(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "account deleted"})
 }
+
+// ownedAccount loads the account and checks it belongs to the authenticated
+// user, recording the error itself when it does not.
+func (s *Server) ownedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		ctx.Error(err)
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		ctx.Error(errAccountNotOwned)
+		return account, false
+	}
+
+	return account, true
+}
@@ -9,10 +9,14 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	mockdb "github.com/NoahFola/simple_bank/db/mock"
 	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,12 +42,16 @@ func TestGetAccountByID(t *testing.T) {
 	tests := []struct {
 		name          string
 		accountID     int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
 	}{
 		{
 			name:      "OK",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).Return(account, nil)
@@ -54,12 +62,41 @@ func TestGetAccountByID(t *testing.T) {
 				require.Equal(t, account, got)
 			},
 		},
+		{
+			name:      "Forbidden_NotOwner",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "bola", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:      "Unauthorized_NoToken",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
 		{
 			name:      "NotFound",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
-					Times(1).Return(db.Account{}, sql.ErrNoRows)
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, rr.Code)
@@ -68,6 +105,9 @@ func TestGetAccountByID(t *testing.T) {
 		{
 			name:      "InternalError",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).Return(db.Account{}, sql.ErrConnDone)
@@ -79,6 +119,9 @@ func TestGetAccountByID(t *testing.T) {
 		{
 			name:      "BadRequest_InvalidID",
 			accountID: -1,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
@@ -95,11 +138,12 @@ func TestGetAccountByID(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tt.buildStubs(store)
 
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			rr := httptest.NewRecorder()
 			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", tt.accountID), nil)
 			require.NoError(t, err)
 
+			tt.setupAuth(t, req, server.tokenMaker)
 			server.router.ServeHTTP(rr, req)
 			tt.checkResponse(t, rr)
 		})
@@ -109,20 +153,23 @@ func TestGetAccountByID(t *testing.T) {
 // -------------------- POST /accounts --------------------
 func TestCreateAccount(t *testing.T) {
 	reqBody := map[string]any{
-		"owner":    "fola",
-		"currency": "USD", // only USD or EUR allowed
+		"currency": "USD", // only USD, EUR or CAD allowed
 	}
 	want := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 0}
 
 	tests := []struct {
 		name          string
 		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK",
 			body: reqBody,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.CreateAccountParams{Owner: "fola", Currency: "USD", Balance: 0}
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).
@@ -134,9 +181,39 @@ func TestCreateAccount(t *testing.T) {
 				require.Equal(t, want, got)
 			},
 		},
+		{
+			name: "OK_CAD",
+			body: map[string]any{"currency": "CAD"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{Owner: "fola", Currency: "CAD", Balance: 0}
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(db.Account{ID: 2, Owner: "fola", Currency: "CAD"}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "Unauthorized_NoToken",
+			body: reqBody,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
 		{
 			name: "BadRequest_MissingFields",
-			body: map[string]any{"owner": "fola"},
+			body: map[string]any{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
@@ -146,17 +223,52 @@ func TestCreateAccount(t *testing.T) {
 		},
 		{
 			name: "BadRequest_InvalidCurrency",
-			body: map[string]any{"owner": "fola", "currency": "NGN"},
+			body: map[string]any{"currency": "NGN"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Contains(t, rr.Body.String(), "must be one of USD, EUR, CAD")
+			},
+		},
+		{
+			name: "Forbidden_UnknownOwner",
+			body: reqBody,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.Account{}, db.TranslateError(&pq.Error{Code: "23503"}))
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "Conflict_DuplicateCurrency",
+			body: reqBody,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.Account{}, db.TranslateError(&pq.Error{Code: "23505"}))
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rr.Code)
 			},
 		},
 		{
 			name: "InternalError_DB",
 			body: reqBody,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "fola", time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
 					Times(1).Return(db.Account{}, sql.ErrConnDone)
@@ -174,7 +286,7 @@ func TestCreateAccount(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tt.buildStubs(store)
 
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			rr := httptest.NewRecorder()
 
 			payload, _ := json.Marshal(tt.body)
@@ -182,6 +294,7 @@ func TestCreateAccount(t *testing.T) {
 			require.NoError(t, err)
 			req.Header.Set("Content-Type", "application/json")
 
+			tt.setupAuth(t, req, server.tokenMaker)
 			server.router.ServeHTTP(rr, req)
 			tt.checkResponse(t, rr)
 		})
@@ -190,15 +303,23 @@ func TestCreateAccount(t *testing.T) {
 
 // -------------------- GET /accounts/ (list) --------------------
 func TestListAccounts(t *testing.T) {
+	owner := "fola"
 	accts := []db.Account{
-		{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000},
-		{ID: 2, Owner: "bola", Currency: "EUR", Balance: 2000},
+		{ID: 1, Owner: owner, Currency: "USD", Balance: 1000},
+		{ID: 2, Owner: owner, Currency: "EUR", Balance: 2000},
+	}
+
+	var manyAccts []db.Account
+	for i := int64(1); i <= 7; i++ {
+		manyAccts = append(manyAccts, db.Account{ID: i, Owner: owner, Currency: "USD", Balance: 1000})
 	}
 
 	tests := []struct {
 		name          string
 		pageID        string
 		pageSize      string
+		cursor        string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
 	}{
@@ -206,13 +327,17 @@ func TestListAccounts(t *testing.T) {
 			name:     "OK_Page1_Size5",
 			pageID:   "1",
 			pageSize: "5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), db.ListAccountsParams{Limit: 5, Offset: 0}).
+					ListAccountsByOwner(gomock.Any(), db.ListAccountsByOwnerParams{Owner: owner, Limit: 5, Offset: 0}).
 					Times(1).Return(accts, nil)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, rr.Code)
+				require.Equal(t, "true", rr.Header().Get("Deprecation"))
 				got := decodeAccounts(t, rr.Body)
 				require.Equal(t, accts, got)
 			},
@@ -221,9 +346,12 @@ func TestListAccounts(t *testing.T) {
 			name:     "OK_Page3_Size10",
 			pageID:   "3",
 			pageSize: "10",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), db.ListAccountsParams{Limit: 10, Offset: 20}).
+					ListAccountsByOwner(gomock.Any(), db.ListAccountsByOwnerParams{Owner: owner, Limit: 10, Offset: 20}).
 					Times(1).Return(accts[:1], nil)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
@@ -232,12 +360,100 @@ func TestListAccounts(t *testing.T) {
 				require.Equal(t, accts[:1], got)
 			},
 		},
+		{
+			name:     "Unauthorized_NoToken",
+			pageID:   "1",
+			pageSize: "5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:     "OK_FirstPage_Cursor",
+			pageSize: "5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwnerAfter(gomock.Any(), db.ListAccountsByOwnerAfterParams{Owner: owner, AfterID: 0, Limit: 6}).
+					Times(1).Return(manyAccts, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.Empty(t, rr.Header().Get("Deprecation"))
+
+				var got pageResponse[db.Account]
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, manyAccts[:5], got.Items)
+				require.Equal(t, encodeCursor(manyAccts[4].ID), got.NextCursor)
+			},
+		},
+		{
+			name:     "OK_LastPage_Cursor",
+			pageSize: "5",
+			cursor:   encodeCursor(5),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwnerAfter(gomock.Any(), db.ListAccountsByOwnerAfterParams{Owner: owner, AfterID: 5, Limit: 6}).
+					Times(1).Return(manyAccts[5:], nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+
+				var got pageResponse[db.Account]
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, manyAccts[5:], got.Items)
+				require.Empty(t, got.NextCursor)
+			},
+		},
+		{
+			name:     "BadRequest_InvalidCursor",
+			pageSize: "5",
+			cursor:   "not-a-cursor",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwnerAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:     "BadRequest_CursorAndPageID",
+			pageID:   "1",
+			pageSize: "5",
+			cursor:   encodeCursor(5),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsByOwnerAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
 		{
 			name:     "BadRequest_MissingParams",
-			pageID:   "",  // required
-			pageSize: "5", // present
+			pageID:   "1", // present
+			pageSize: "",  // required
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, rr.Code)
@@ -247,8 +463,11 @@ func TestListAccounts(t *testing.T) {
 			name:     "BadRequest_OutOfRange",
 			pageID:   "0",  // min=1
 			pageSize: "11", // max=10
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, rr.Code)
@@ -258,9 +477,12 @@ func TestListAccounts(t *testing.T) {
 			name:     "InternalError_DB",
 			pageID:   "1",
 			pageSize: "5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), db.ListAccountsParams{Limit: 5, Offset: 0}).
+					ListAccountsByOwner(gomock.Any(), db.ListAccountsByOwnerParams{Owner: owner, Limit: 5, Offset: 0}).
 					Times(1).Return(nil, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
@@ -276,14 +498,15 @@ func TestListAccounts(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tt.buildStubs(store)
 
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			rr := httptest.NewRecorder()
 
 			// NOTE: your router registered "/accounts/" (with trailing slash)
-			url := "/accounts/?page_id=" + tt.pageID + "&page_size=" + tt.pageSize
+			url := "/accounts/?page_id=" + tt.pageID + "&page_size=" + tt.pageSize + "&cursor=" + tt.cursor
 			req, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
+			tt.setupAuth(t, req, server.tokenMaker)
 			server.router.ServeHTTP(rr, req)
 			tt.checkResponse(t, rr)
 		})
@@ -292,12 +515,21 @@ func TestListAccounts(t *testing.T) {
 
 // -------------------- PATCH /accounts/:id --------------------
 func TestUpdateAccount(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
 	want := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 5000}
+	admin := db.User{Username: "admin", Role: util.AdminRole}
+	depositor := db.User{Username: account.Owner, Role: util.DepositorRole}
+
+	result := db.AccountTxResult{
+		Account: want,
+		Entry:   db.Entry{ID: 1, AccountID: account.ID, Amount: want.Balance - account.Balance},
+	}
 
 	tests := []struct {
 		name          string
 		id            string
 		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
 	}{
@@ -305,23 +537,99 @@ func TestUpdateAccount(t *testing.T) {
 			name: "OK",
 			id:   strconv.FormatInt(want.ID, 10),
 			body: map[string]any{"balance": want.Balance},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.UpdateAccountParams{ID: want.ID, Balance: want.Balance}
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Eq(arg)).
-					Times(1).Return(want, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				arg := db.AdjustBalanceTxParams{AccountID: want.ID, Balance: want.Balance}
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, rr.Code)
-				got := decodeAccount(t, rr.Body)
-				require.Equal(t, want, got)
+				var got db.AccountTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "OK_ZeroBalance",
+			id:   strconv.FormatInt(account.ID, 10),
+			body: map[string]any{"balance": 0},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				arg := db.AdjustBalanceTxParams{AccountID: account.ID, Balance: 0}
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(db.AccountTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "Forbidden_NotAdmin",
+			id:   "1",
+			body: map[string]any{"balance": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, depositor.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(depositor.Username)).
+					Times(1).Return(depositor, nil)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "Unauthorized_UnknownUser",
+			id:   "1",
+			body: map[string]any{"balance": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "ghost", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("ghost")).
+					Times(1).Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "Unauthorized_NoToken",
+			id:   "1",
+			body: map[string]any{"balance": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
 			},
 		},
 		{
 			name: "BadRequest_InvalidID",
 			id:   "abc",
 			body: map[string]any{"balance": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, rr.Code)
@@ -331,35 +639,47 @@ func TestUpdateAccount(t *testing.T) {
 			name: "BadRequest_MissingBalance",
 			id:   "1",
 			body: map[string]any{"bal": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, rr.Code)
 			},
 		},
 		{
-			name: "ErrNoRows_Currently500",
+			name: "NotFound",
 			id:   "1",
 			body: map[string]any{"balance": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					UpdateAccount(gomock.Any(), db.UpdateAccountParams{ID: 1, Balance: 500}).
-					Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.AccountTxResult{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
-				// Your handler returns 500 on ErrNoRows right now
-				require.Equal(t, http.StatusInternalServerError, rr.Code)
+				require.Equal(t, http.StatusNotFound, rr.Code)
 			},
 		},
 		{
 			name: "InternalError_DB",
 			id:   "1",
 			body: map[string]any{"balance": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().
-					UpdateAccount(gomock.Any(), db.UpdateAccountParams{ID: 1, Balance: 500}).
-					Times(1).Return(db.Account{}, sql.ErrConnDone)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().AdjustBalanceTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.AccountTxResult{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, rr.Code)
@@ -374,7 +694,7 @@ func TestUpdateAccount(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tt.buildStubs(store)
 
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			rr := httptest.NewRecorder()
 
 			payload, _ := json.Marshal(tt.body)
@@ -382,6 +702,7 @@ func TestUpdateAccount(t *testing.T) {
 			require.NoError(t, err)
 			req.Header.Set("Content-Type", "application/json")
 
+			tt.setupAuth(t, req, server.tokenMaker)
 			server.router.ServeHTTP(rr, req)
 			tt.checkResponse(t, rr)
 		})
@@ -390,16 +711,24 @@ func TestUpdateAccount(t *testing.T) {
 
 // -------------------- DELETE /accounts/:id --------------------
 func TestDeleteAccount(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+
 	tests := []struct {
 		name          string
 		id            string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK_Returns200",
 			id:   "1",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).
+					Times(1).Return(account, nil)
 				store.EXPECT().DeleteAccount(gomock.Any(), int64(1)).
 					Times(1).Return(nil)
 			},
@@ -407,9 +736,40 @@ func TestDeleteAccount(t *testing.T) {
 				require.Equal(t, http.StatusOK, rr.Code) // handler returns 200 + JSON message
 			},
 		},
+		{
+			name: "Forbidden_NotOwner",
+			id:   "1",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "bola", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).
+					Times(1).Return(account, nil)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "Unauthorized_NoToken",
+			id:   "1",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
 		{
 			name: "BadRequest_InvalidID",
 			id:   "x",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
@@ -420,9 +780,13 @@ func TestDeleteAccount(t *testing.T) {
 		{
 			name: "NotFound",
 			id:   "2",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().DeleteAccount(gomock.Any(), int64(2)).
-					Times(1).Return(sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), int64(2)).
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, rr.Code)
@@ -431,7 +795,12 @@ func TestDeleteAccount(t *testing.T) {
 		{
 			name: "InternalError_DB",
 			id:   "3",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(3)).
+					Times(1).Return(db.Account{ID: 3, Owner: account.Owner}, nil)
 				store.EXPECT().DeleteAccount(gomock.Any(), int64(3)).
 					Times(1).Return(sql.ErrConnDone)
 			},
@@ -448,12 +817,257 @@ func TestDeleteAccount(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tt.buildStubs(store)
 
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			rr := httptest.NewRecorder()
 
 			req, err := http.NewRequest(http.MethodDelete, "/accounts/"+tt.id, nil)
 			require.NoError(t, err)
 
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- POST /accounts/:id/deposits --------------------
+func TestCreateDeposit(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	result := db.AccountTxResult{
+		Account: db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1200},
+		Entry:   db.Entry{ID: 1, AccountID: account.ID, Amount: 200},
+	}
+	admin := db.User{Username: "admin", Role: util.AdminRole}
+	depositor := db.User{Username: account.Owner, Role: util.DepositorRole}
+
+	tests := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.DepositTxParams{AccountID: account.ID, Amount: 200}
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.AccountTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "Forbidden_NotAdmin",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, depositor.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(depositor.Username)).
+					Times(1).Return(depositor, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_NonPositiveAmount",
+			body: map[string]any{"amount": -5},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "InternalError_DB",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).Return(admin, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.AccountTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			payload, _ := json.Marshal(tt.body)
+			url := fmt.Sprintf("/accounts/%d/deposits", account.ID)
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- POST /accounts/:id/withdrawals --------------------
+func TestCreateWithdrawal(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	result := db.AccountTxResult{
+		Account: db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 800},
+		Entry:   db.Entry{ID: 1, AccountID: account.ID, Amount: -200},
+	}
+
+	tests := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.WithdrawTxParams{AccountID: account.ID, Amount: 200}
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.AccountTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: map[string]any{"amount": 5000},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.AccountTxResult{}, &db.InsufficientFundsError{
+					AccountID: account.ID,
+					Available: account.Balance,
+					Requested: 5000,
+				})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+				var got map[string]any
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.EqualValues(t, account.Balance, got["available_balance"])
+			},
+		},
+		{
+			name: "Forbidden_NotOwner",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "bola", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: map[string]any{"amount": 200},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			payload, _ := json.Marshal(tt.body)
+			url := fmt.Sprintf("/accounts/%d/withdrawals", account.ID)
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			tt.setupAuth(t, req, server.tokenMaker)
 			server.router.ServeHTTP(rr, req)
 			tt.checkResponse(t, rr)
 		})
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// -------------------- GET /accounts/:id/entries --------------------
+func TestListAccountEntries(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	transferID := int64(9)
+	entries := []db.Entry{
+		{ID: 1, AccountID: account.ID, Amount: 100, EntryType: db.EntryTypeDeposit},
+		{ID: 2, AccountID: account.ID, Amount: -50, EntryType: db.EntryTypeTransferDebit, TransferID: &transferID},
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name          string
+		accountID     int64
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     "page_id=2&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListEntriesByAccountParams{AccountID: account.ID, Limit: 5, Offset: 5}
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got []db.Entry
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, entries, got)
+
+				var raw []map[string]any
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &raw))
+				require.Equal(t, "deposit", raw[0]["entry_type"])
+				require.Nil(t, raw[0]["transfer_id"])
+				require.Equal(t, "transfer_debit", raw[1]["entry_type"])
+				require.EqualValues(t, transferID, raw[1]["transfer_id"])
+			},
+		},
+		{
+			name:      "OK_Cursor",
+			accountID: account.ID,
+			query:     "page_size=5&cursor=" + encodeCursor(10),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListEntriesByAccountAfterParams{AccountID: account.ID, AfterID: 10, Limit: 6}
+				store.EXPECT().ListEntriesByAccountAfter(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got pageResponse[db.Entry]
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, entries, got.Items)
+				require.Empty(t, got.NextCursor)
+			},
+		},
+		{
+			name:      "OK_TimeRange",
+			accountID: account.ID,
+			query: fmt.Sprintf("page_id=1&page_size=5&start_time=%s&end_time=%s",
+				start.Format(time.RFC3339), end.Format(time.RFC3339)),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ListEntriesByAccountParams) ([]db.Entry, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.True(t, arg.StartTime.Valid)
+						require.True(t, arg.StartTime.Time.Equal(start))
+						require.True(t, arg.EndTime.Valid)
+						require.True(t, arg.EndTime.Time.Equal(end))
+						return entries, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:      "BadRequest_EndBeforeStart",
+			accountID: account.ID,
+			query: fmt.Sprintf("page_id=1&page_size=5&start_time=%s&end_time=%s",
+				end.Format(time.RFC3339), start.Format(time.RFC3339)),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:      "BadRequest_MissingPageSize",
+			accountID: account.ID,
+			query:     "page_id=1",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:      "Forbidden_NotOwner",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "someoneelse", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name:      "InternalError_DB",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).
+					Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", tt.accountID, tt.query)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
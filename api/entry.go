@@ -0,0 +1,90 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// timeRangeQuery filters a list by creation time. Either bound may be
+// omitted; StartTime is inclusive and EndTime exclusive.
+type timeRangeQuery struct {
+	StartTime time.Time `form:"start_time"`
+	EndTime   time.Time `form:"end_time" binding:"omitempty,gtfield=StartTime"`
+}
+
+func (q timeRangeQuery) bounds() (start, end sql.NullTime) {
+	start = sql.NullTime{Time: q.StartTime, Valid: !q.StartTime.IsZero()}
+	end = sql.NullTime{Time: q.EndTime, Valid: !q.EndTime.IsZero()}
+	return
+}
+
+type listAccountEntriesURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listAccountEntriesRequest struct {
+	pageRequest
+	timeRangeQuery
+}
+
+func (s *Server) listAccountEntries(ctx *gin.Context) {
+	var uri listAccountEntriesURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req listAccountEntriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	start, end := req.bounds()
+	if req.usesOffset(ctx) {
+		arg := db.ListEntriesByAccountParams{
+			AccountID: uri.ID,
+			StartTime: start,
+			EndTime:   end,
+			Limit:     req.PageSize,
+			Offset:    req.offset(),
+		}
+		entries, err := s.store.ListEntriesByAccount(ctx, arg)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, entries)
+		return
+	}
+
+	afterID, err := req.afterID()
+	if err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	arg := db.ListEntriesByAccountAfterParams{
+		AccountID: uri.ID,
+		AfterID:   afterID,
+		StartTime: start,
+		EndTime:   end,
+		Limit:     req.limit(),
+	}
+	entries, err := s.store.ListEntriesByAccountAfter(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(entries, req.PageSize, func(e db.Entry) int64 { return e.ID }))
+}
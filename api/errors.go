@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const problemContentType = "application/problem+json"
+
+// errorMiddleware writes the last error a handler recorded with ctx.Error as
+// an RFC 7807 problem. The code of the error picks the status; errors
+// without one are reported as internal errors without their message.
+func errorMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+		writeProblem(ctx, ctx.Errors.Last().Err)
+	}
+}
+
+// writeProblem aborts the request with err as an RFC 7807 problem. Details
+// the error carries for clients, such as the balance still available, are
+// added as extension members.
+func writeProblem(ctx *gin.Context, err error) {
+	code := apperr.CodeOf(err)
+	status := code.Status()
+
+	body := gin.H{}
+	for k, v := range apperr.ExtensionsOf(err) {
+		body[k] = v
+	}
+
+	body["type"] = "about:blank"
+	body["title"] = http.StatusText(status)
+	body["status"] = status
+	body["code"] = code
+	body["detail"] = apperr.Detail(err)
+	body["instance"] = ctx.Request.URL.Path
+	if requestID := util.RequestIDFromContext(ctx.Request.Context()); requestID != "" {
+		body["request_id"] = requestID
+	}
+	if appErr, ok := apperr.As(err); ok && len(appErr.Fields) > 0 {
+		body["errors"] = appErr.Fields
+	}
+
+	ctx.Abort()
+	ctx.Render(status, problemRender{body})
+}
+
+// problemRender writes a problem as JSON with the problem content type
+type problemRender struct {
+	body gin.H
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.body)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", problemContentType)
+}
+
+// abortWithError records err and stops the handler chain; errorMiddleware
+// then writes it. Middlewares use it where handlers would simply return.
+func abortWithError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+}
+
+// validationError turns an error from binding a request into a
+// validation_failed error listing the fields that were rejected.
+func validationError(err error) error {
+	if _, ok := apperr.As(err); ok {
+		return err
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]apperr.FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = apperr.FieldError{
+				Field:   fieldName(fe),
+				Message: fieldMessage(fe),
+			}
+		}
+		return &apperr.Error{
+			Code:    apperr.ValidationFailed,
+			Message: "request is invalid",
+			Fields:  fields,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &apperr.Error{
+			Code:    apperr.ValidationFailed,
+			Message: "request is invalid",
+			Fields: []apperr.FieldError{{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be a %s", typeErr.Type.Kind()),
+			}},
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return apperr.New(apperr.ValidationFailed, "request body is not valid JSON")
+	}
+
+	return apperr.New(apperr.ValidationFailed, err.Error())
+}
+
+// embeddedFieldName names embedded structs, such as pageRequest, whose
+// fields clients send as if they were declared on the outer struct.
+const embeddedFieldName = "_"
+
+// fieldName returns the path of the rejected field as the client sent it,
+// e.g. items[2].amount, dropping the name of the request struct and of any
+// embedded structs.
+func fieldName(fe validator.FieldError) string {
+	segments := strings.Split(fe.Namespace(), ".")
+	path := segments[:0]
+	for _, segment := range segments[1:] {
+		if segment != embeddedFieldName {
+			path = append(path, segment)
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "email":
+		return "must be an email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "nefield":
+		return "must differ from " + fe.Param()
+	case "gtfield":
+		return "must be after " + fe.Param()
+	case "excluded_with":
+		return "cannot be combined with " + fe.Param()
+	case "currency":
+		return "must be one of " + strings.Join(util.SupportedCurrencies, ", ")
+	default:
+		return "failed the " + fe.Tag() + " check"
+	}
+}
+
+var registerValidationOnce sync.Once
+
+// registerValidation makes the validator name fields after their json, form
+// or uri tag, so errors name fields the way clients spell them, and adds
+// the currency tag accepting any supported currency.
+func registerValidation() {
+	registerValidationOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+			return util.IsSupportedCurrency(fl.Field().String())
+		})
+
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			if field.Anonymous {
+				return embeddedFieldName
+			}
+			for _, key := range []string{"json", "form", "uri"} {
+				name := strings.SplitN(field.Tag.Get(key), ",", 2)[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	})
+}
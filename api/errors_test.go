@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// -------------------- helpers --------------------
+type problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Code      apperr.Code         `json:"code"`
+	Detail    string              `json:"detail"`
+	Instance  string              `json:"instance"`
+	RequestID string              `json:"request_id"`
+	Errors    []apperr.FieldError `json:"errors"`
+}
+
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) problem {
+	t.Helper()
+	require.Equal(t, problemContentType, rr.Header().Get("Content-Type"))
+
+	var p problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p))
+	require.Equal(t, "about:blank", p.Type)
+	require.Equal(t, rr.Code, p.Status)
+	require.Equal(t, http.StatusText(rr.Code), p.Title)
+	return p
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "NotFound",
+			err:  fmt.Errorf("account [7]: %w", db.ErrRecordNotFound),
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+				p := decodeProblem(t, rr)
+				require.Equal(t, apperr.NotFound, p.Code)
+				require.Equal(t, "account [7]: record not found", p.Detail)
+				require.Equal(t, "/fail", p.Instance)
+				require.Equal(t, "req-1", p.RequestID)
+			},
+		},
+		{
+			name: "Extensions",
+			err:  &db.InsufficientFundsError{AccountID: 1, Available: 5, Requested: 10},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+				p := decodeProblem(t, rr)
+				require.Equal(t, apperr.InsufficientFunds, p.Code)
+
+				var body map[string]any
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.EqualValues(t, 5, body["available_balance"])
+			},
+		},
+		{
+			name: "InternalErrorHidden",
+			err:  errors.New("dial tcp 10.0.0.3:5432: connection refused"),
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+				p := decodeProblem(t, rr)
+				require.Equal(t, apperr.Internal, p.Code)
+				require.Equal(t, "internal server error", p.Detail)
+				require.NotContains(t, rr.Body.String(), "10.0.0.3")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(requestIDMiddleware(), errorMiddleware())
+			router.GET("/fail", func(ctx *gin.Context) {
+				ctx.Error(tt.err)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			request.Header.Set(requestIDHeaderKey, "req-1")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+func TestValidationErrorFields(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		fields []apperr.FieldError
+	}{
+		{
+			name:   "NestedBodyField",
+			method: http.MethodPost,
+			url:    "/transfers/batch",
+			body:   `{"from_account_id":1,"currency":"USD","mode":"best_effort","items":[{"to_account_id":2,"amount":0}]}`,
+			fields: []apperr.FieldError{{Field: "items[0].amount", Message: "is required"}},
+		},
+		{
+			name:   "WrongType",
+			method: http.MethodPost,
+			url:    "/transfers/batch",
+			body:   `{"from_account_id":"one"}`,
+			fields: []apperr.FieldError{{Field: "from_account_id", Message: "must be a int64"}},
+		},
+		{
+			name:   "QueryParam",
+			method: http.MethodGet,
+			url:    "/accounts/?page_size=50",
+			fields: []apperr.FieldError{{Field: "page_size", Message: "must be at most 10"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, request)
+
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			p := decodeProblem(t, rr)
+			require.Equal(t, apperr.ValidationFailed, p.Code)
+			require.Equal(t, tt.fields, p.Errors)
+		})
+	}
+}
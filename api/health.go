@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds the database checks of a readiness probe so that a
+// hung database fails the probe instead of stalling it.
+const readinessTimeout = 2 * time.Second
+
+// Readiness check results. Only these fixed values are reported, since the
+// probe is open to unauthenticated callers; the cause is logged instead.
+const (
+	checkOK          = "ok"
+	checkUnreachable = "unreachable"
+	checkUnknown     = "unknown"
+	checkDirty       = "dirty"
+	checkOutdated    = "outdated"
+)
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// healthz reports that the process is up and serving requests. It does not
+// touch the database, so a database outage doesn't get the process killed.
+func (s *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz reports whether the server can handle requests: the database must
+// answer and be migrated at least to the version the code expects.
+func (s *Server) readyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	rsp := readinessResponse{
+		Status: "ok",
+		Checks: map[string]string{"database": checkOK, "migrations": checkOK},
+	}
+
+	if err := s.store.Ping(checkCtx); err != nil {
+		s.logger.WarnContext(ctx, "database is unreachable", "error", err)
+		rsp.Checks["database"] = checkUnreachable
+		rsp.Checks["migrations"] = checkUnknown
+	} else if result, err := s.checkMigrations(checkCtx); err != nil {
+		s.logger.WarnContext(ctx, "database schema is not ready", "error", err)
+		rsp.Checks["migrations"] = result
+	}
+
+	status := http.StatusOK
+	if rsp.Checks["database"] != checkOK || rsp.Checks["migrations"] != checkOK {
+		rsp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, rsp)
+}
+
+// checkMigrations fails unless the database is at the configured schema
+// version or later and its last migration completed. It returns the check result to report
+// along with the reason for a failure.
+func (s *Server) checkMigrations(ctx context.Context) (string, error) {
+	version, err := s.store.MigrationVersion(ctx)
+	if err != nil {
+		return checkUnknown, fmt.Errorf("cannot read migration version: %w", err)
+	}
+	if version.Dirty {
+		return checkDirty, fmt.Errorf("migration %d is dirty", version.Version)
+	}
+	if version.Version < s.config.SchemaVersion {
+		return checkOutdated, fmt.Errorf("at version %d, need %d", version.Version, s.config.SchemaVersion)
+	}
+	return checkOK, nil
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// -------------------- GET /healthz --------------------
+func TestHealthz(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().Ping(gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+// -------------------- GET /readyz --------------------
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).
+					Return(db.MigrationVersion{Version: testSchemaVersion}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				rsp := decodeReadiness(t, rr)
+				require.Equal(t, "ok", rsp.Status)
+			},
+		},
+		{
+			name: "NewerSchema",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).
+					Return(db.MigrationVersion{Version: testSchemaVersion + 1}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "DatabaseUnreachable",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(errors.New("connection refused"))
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+				rsp := decodeReadiness(t, rr)
+				require.Equal(t, "unavailable", rsp.Status)
+				require.Equal(t, "unreachable", rsp.Checks["database"])
+			},
+		},
+		{
+			name: "SchemaBehind",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).
+					Return(db.MigrationVersion{Version: testSchemaVersion - 1}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+				rsp := decodeReadiness(t, rr)
+				require.Equal(t, "ok", rsp.Checks["database"])
+				require.Equal(t, "outdated", rsp.Checks["migrations"])
+			},
+		},
+		{
+			name: "DirtySchema",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).
+					Return(db.MigrationVersion{Version: testSchemaVersion, Dirty: true}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+				rsp := decodeReadiness(t, rr)
+				require.Equal(t, "dirty", rsp.Checks["migrations"])
+			},
+		},
+		{
+			name: "NeverMigrated",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).
+					Return(db.MigrationVersion{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+				rsp := decodeReadiness(t, rr)
+				require.Equal(t, "unknown", rsp.Checks["migrations"])
+				require.NotContains(t, rr.Body.String(), "record not found")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+func decodeReadiness(t *testing.T, rr *httptest.ResponseRecorder) readinessResponse {
+	t.Helper()
+	var rsp readinessResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+	return rsp
+}
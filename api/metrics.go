@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/NoahFola/simple_bank/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newRequestDuration registers the histogram of request latencies by
+// method, route and status.
+func newRequestDuration(registerer prometheus.Registerer) (*prometheus.HistogramVec, error) {
+	requestDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	if err := registerer.Register(requestDuration); err != nil {
+		return nil, err
+	}
+	return requestDuration, nil
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+	requestIDHeaderKey      = "X-Request-ID"
+
+	// tracerName names the tracer of the request spans
+	tracerName = "github.com/NoahFola/simple_bank/api"
+	// unmatchedRoute names requests that matched no route in metrics and
+	// spans, so that probing random paths cannot create new series.
+	unmatchedRoute = "unmatched"
+)
+
+// validRequestID matches the request IDs accepted from clients; anything
+// else is replaced so it cannot garble the logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// tracingMiddleware starts a server span for each request, continuing the
+// trace of the caller when it sent a W3C traceparent header. Store calls made
+// with the request context become its children.
+func tracingMiddleware(tracer trace.Tracer, propagator propagation.TextMapPropagator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		parent := propagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		spanCtx, span := tracer.Start(parent, ctx.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(ctx.Request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if requestID := util.RequestIDFromContext(ctx.Request.Context()); requestID != "" {
+			span.SetAttributes(attribute.String("request_id", requestID))
+		}
+		if len(ctx.Errors) > 0 {
+			span.RecordError(ctx.Errors.Last().Err)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// requestIDMiddleware tags the request with the ID sent in the X-Request-ID
+// header, or a new one, and echoes it in the response. The ID is carried by
+// the request context so that anything logged while serving the request,
+// store calls included, is tagged with it.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Request = ctx.Request.WithContext(util.ContextWithRequestID(ctx.Request.Context(), requestID))
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Next()
+	}
+}
+
+// accessLogMiddleware logs one record per request once it has been served.
+// Server errors are logged at error level and client errors at warn level.
+func accessLogMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Int("size", ctx.Writer.Size()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", ctx.Errors.String()))
+		}
+
+		logger.LogAttrs(ctx.Request.Context(), level, "request served", attrs...)
+	}
+}
+
+// metricsMiddleware observes the latency of each request in requestDuration
+// once it has been served.
+func metricsMiddleware(requestDuration *prometheus.HistogramVec) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		requestDuration.
+			WithLabelValues(ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// recoveryMiddleware turns a panic in a handler into a 500 response and
+// logs it with the request.
+func recoveryMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, recovered any) {
+		logger.ErrorContext(ctx.Request.Context(), "handler panicked", "panic", recovered)
+		writeProblem(ctx, fmt.Errorf("panic: %v", recovered))
+	})
+}
+
+// authMiddleware verifies the bearer token on the request and stores its
+// payload in the context under authorizationPayloadKey.
+func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			abortWithError(ctx, apperr.New(apperr.Unauthorized, "authorization header is not provided"))
+			return
+		}
+
+		fields := strings.Fields(authorizationHeader)
+		if len(fields) < 2 {
+			abortWithError(ctx, apperr.New(apperr.Unauthorized, "invalid authorization header format"))
+			return
+		}
+
+		authorizationType := strings.ToLower(fields[0])
+		if authorizationType != authorizationTypeBearer {
+			abortWithError(ctx, apperr.New(apperr.Unauthorized, fmt.Sprintf("unsupported authorization type %s", authorizationType)))
+			return
+		}
+
+		accessToken := fields[1]
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccess)
+		if err != nil {
+			abortWithError(ctx, apperr.Wrap(err, apperr.Unauthorized, "invalid access token"))
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// roleMiddleware only lets through users holding the given role. The role is
+// read from the database on every request so a demotion takes effect
+// immediately. It must run after authMiddleware.
+func roleMiddleware(store db.Store, role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		user, err := store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			if apperr.CodeOf(err) == apperr.NotFound {
+				abortWithError(ctx, apperr.Wrap(err, apperr.Unauthorized, "user no longer exists"))
+				return
+			}
+			abortWithError(ctx, err)
+			return
+		}
+
+		if user.Role != role {
+			abortWithError(ctx, apperr.New(apperr.Forbidden, fmt.Sprintf("user doesn't have the %s role", role)))
+			return
+		}
+
+		ctx.Next()
+	}
+}
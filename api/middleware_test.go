@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// -------------------- helpers --------------------
+func addAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	duration time.Duration,
+) {
+	t.Helper()
+	accessToken, payload, err := tokenMaker.CreateToken(username, token.TokenTypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, accessToken)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "UnsupportedAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "unsupported", "user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "InvalidAuthorizationFormat",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "", "user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				refreshToken, _, err := tokenMaker.CreateToken("user", token.TokenTypeRefresh, time.Minute)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, refreshToken))
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "Propagated",
+			requestID: "abc-123",
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, "abc-123", rr.Header().Get(requestIDHeaderKey))
+				require.Equal(t, `"abc-123"`, rr.Body.String())
+			},
+		},
+		{
+			name: "Generated",
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				requestID := rr.Header().Get(requestIDHeaderKey)
+				require.Len(t, requestID, 36)
+				require.Equal(t, `"`+requestID+`"`, rr.Body.String())
+			},
+		},
+		{
+			name:      "Replaced",
+			requestID: "bad id\nwith=newline",
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Len(t, rr.Header().Get(requestIDHeaderKey), 36)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			// The handler reads the ID through the gin context, as store
+			// calls do.
+			server.router.GET("/request_id", func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, util.RequestIDFromContext(ctx))
+			})
+
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/request_id", nil)
+			require.NoError(t, err)
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeaderKey, tt.requestID)
+			}
+
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := util.NewLogger(&buf, "info", util.LogFormatJSON)
+	require.NoError(t, err)
+
+	config := util.Config{TokenSymmetricKey: util.RandomString(32)}
+	server, err := NewServer(config, nil, logger, nil)
+	require.NoError(t, err)
+
+	server.router.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/panic", nil)
+	require.NoError(t, err)
+	req.Header.Set(requestIDHeaderKey, "req-42")
+
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	require.Equal(t, "request served", record["msg"])
+	require.Equal(t, "ERROR", record["level"])
+	require.Equal(t, "req-42", record["request_id"])
+	require.Equal(t, "/panic", record["route"])
+	require.Equal(t, float64(http.StatusInternalServerError), record["status"])
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		url         string
+		traceparent string
+		checkSpan   func(t *testing.T, span sdktrace.ReadOnlySpan)
+	}{
+		{
+			name:        "ContinuesTrace",
+			url:         "/accounts/7",
+			traceparent: "00-" + traceID + "-" + parentSpanID + "-01",
+			checkSpan: func(t *testing.T, span sdktrace.ReadOnlySpan) {
+				require.Equal(t, "GET /accounts/:id", span.Name())
+				require.Equal(t, traceID, span.SpanContext().TraceID().String())
+				require.Equal(t, parentSpanID, span.Parent().SpanID().String())
+				require.True(t, span.Parent().IsRemote())
+				require.Equal(t, codes.Unset, span.Status().Code)
+			},
+		},
+		{
+			name: "NewTrace",
+			url:  "/accounts/7",
+			checkSpan: func(t *testing.T, span sdktrace.ReadOnlySpan) {
+				require.False(t, span.Parent().IsValid())
+			},
+		},
+		{
+			name: "ServerError",
+			url:  "/fail",
+			checkSpan: func(t *testing.T, span sdktrace.ReadOnlySpan) {
+				require.Equal(t, codes.Error, span.Status().Code)
+				require.Len(t, span.Events(), 1)
+			},
+		},
+		{
+			name: "Unmatched",
+			url:  "/no/such/route",
+			checkSpan: func(t *testing.T, span sdktrace.ReadOnlySpan) {
+				require.Equal(t, "GET "+unmatchedRoute, span.Name())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			router := gin.New()
+			router.Use(tracingMiddleware(provider.Tracer(tracerName), propagation.TraceContext{}), errorMiddleware())
+			router.GET("/accounts/:id", func(ctx *gin.Context) {
+				// Store calls made with the gin context join the request's trace
+				require.True(t, trace.SpanContextFromContext(ctx).IsValid())
+				ctx.Status(http.StatusOK)
+			})
+			router.GET("/fail", func(ctx *gin.Context) {
+				ctx.Error(errors.New("boom"))
+			})
+			router.ContextWithFallback = true
+
+			request := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.traceparent != "" {
+				request.Header.Set("traceparent", tt.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			tt.checkSpan(t, spans[0])
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidCursor = &apperr.Error{
+	Code:    apperr.ValidationFailed,
+	Message: "request is invalid",
+	Fields:  []apperr.FieldError{{Field: "cursor", Message: "is not a cursor returned by this API"}},
+}
+
+// pageRequest selects a page of a list. Clients page forward with the opaque
+// cursor returned as next_cursor; page_id is the deprecated offset-based
+// fallback and cannot be combined with a cursor.
+type pageRequest struct {
+	Cursor   string `form:"cursor"`
+	PageID   int32  `form:"page_id" binding:"omitempty,min=1,excluded_with=Cursor"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// usesOffset reports whether the deprecated page_id pagination was requested.
+// It marks the response as deprecated when it was.
+func (r pageRequest) usesOffset(ctx *gin.Context) bool {
+	if r.PageID == 0 {
+		return false
+	}
+	ctx.Header("Deprecation", "true")
+	return true
+}
+
+func (r pageRequest) offset() int32 {
+	return (r.PageID - 1) * r.PageSize
+}
+
+// afterID decodes the cursor into the last ID of the previous page. An empty
+// cursor starts from the beginning.
+func (r pageRequest) afterID() (int64, error) {
+	if r.Cursor == "" {
+		return 0, nil
+	}
+	return decodeCursor(r.Cursor)
+}
+
+// limit fetches one row past the page so we know whether another page exists.
+func (r pageRequest) limit() int32 {
+	return r.PageSize + 1
+}
+
+type cursor struct {
+	AfterID int64 `json:"after_id"`
+}
+
+func encodeCursor(afterID int64) string {
+	data, _ := json.Marshal(cursor{AfterID: afterID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.AfterID < 1 {
+		return 0, errInvalidCursor
+	}
+	return c.AfterID, nil
+}
+
+// pageResponse is a page of a list plus the cursor of the page after it,
+// which is omitted on the last page.
+type pageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// newPageResponse trims the extra row fetched by pageRequest.limit and turns
+// it into a cursor when it is present.
+func newPageResponse[T any](items []T, pageSize int32, id func(T) int64) pageResponse[T] {
+	rsp := pageResponse[T]{Items: items}
+	if len(items) > int(pageSize) {
+		rsp.Items = items[:pageSize]
+		rsp.NextCursor = encodeCursor(id(rsp.Items[pageSize-1]))
+	}
+	return rsp
+}
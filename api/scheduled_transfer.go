@@ -0,0 +1,268 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	ScheduleUnit  string `json:"schedule_unit" binding:"required,oneof=day week month"`
+	// ScheduleEvery is the number of units between runs; defaults to 1
+	ScheduleEvery int32      `json:"schedule_every" binding:"omitempty,min=1,max=365"`
+	StartAt       time.Time  `json:"start_at" binding:"required"`
+	EndAt         *time.Time `json:"end_at" binding:"omitempty,gtfield=StartAt"`
+}
+
+// createScheduledTransfer sets up a standing order from an account of the
+// authenticated user. Both accounts must hold the same currency since runs
+// are not quoted.
+func (s *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	fromAccount, ok := s.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.Error(errAccountNotOwned)
+		return
+	}
+
+	if _, ok := s.validAccount(ctx, req.ToAccountID, req.Currency); !ok {
+		return
+	}
+
+	if req.ScheduleEvery == 0 {
+		req.ScheduleEvery = 1
+	}
+
+	arg := db.CreateScheduledTransferParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		ScheduleUnit:  db.ScheduleUnit(req.ScheduleUnit),
+		ScheduleEvery: req.ScheduleEvery,
+		StartAt:       req.StartAt,
+		EndAt:         req.EndAt,
+	}
+
+	scheduled, err := s.store.CreateScheduledTransfer(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type scheduledTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	scheduled, ok := s.ownedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type listAccountScheduledTransfersURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listAccountScheduledTransfersRequest struct {
+	pageRequest
+}
+
+// listAccountScheduledTransfers lists the standing orders paid from an account
+func (s *Server) listAccountScheduledTransfers(ctx *gin.Context) {
+	var uri listAccountScheduledTransfersURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req listAccountScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	if req.usesOffset(ctx) {
+		arg := db.ListScheduledTransfersByAccountParams{
+			FromAccountID: uri.ID,
+			Limit:         req.PageSize,
+			Offset:        req.offset(),
+		}
+		scheduled, err := s.store.ListScheduledTransfersByAccount(ctx, arg)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, scheduled)
+		return
+	}
+
+	afterID, err := req.afterID()
+	if err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	arg := db.ListScheduledTransfersByAccountAfterParams{
+		FromAccountID: uri.ID,
+		AfterID:       afterID,
+		Limit:         req.limit(),
+	}
+	scheduled, err := s.store.ListScheduledTransfersByAccountAfter(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(scheduled, req.PageSize, func(st db.ScheduledTransfer) int64 { return st.ID }))
+}
+
+type updateScheduledTransferRequest struct {
+	Amount     *int64     `json:"amount" binding:"omitempty,gt=0"`
+	EndAt      *time.Time `json:"end_at" binding:"excluded_with=ClearEndAt"`
+	ClearEndAt bool       `json:"clear_end_at"`
+	Active     *bool      `json:"active"`
+}
+
+// updateScheduledTransfer changes the amount or end of a standing order, or
+// pauses and resumes it. Fields left out are unchanged. A resumed order
+// skips the runs it missed while paused and must not have ended.
+func (s *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	scheduled, ok := s.ownedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if req.EndAt != nil && !req.EndAt.After(scheduled.StartAt) {
+		ctx.Error(&apperr.Error{
+			Code:    apperr.ValidationFailed,
+			Message: "request is invalid",
+			Fields:  []apperr.FieldError{{Field: "end_at", Message: "must be after start_at"}},
+		})
+		return
+	}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID:         uri.ID,
+		EndAt:      req.EndAt,
+		ClearEndAt: req.ClearEndAt,
+	}
+	if req.Amount != nil {
+		arg.Amount = sql.NullInt64{Int64: *req.Amount, Valid: true}
+	}
+	if req.Active != nil {
+		arg.Active = sql.NullBool{Bool: *req.Active, Valid: true}
+	}
+
+	if req.Active != nil && *req.Active {
+		nextRunAt := scheduled.NextRunAt
+		if !scheduled.Active && !nextRunAt.After(time.Now()) {
+			nextRunAt = scheduled.NextRunAfter(time.Now())
+			arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: true}
+		}
+
+		endAt := scheduled.EndAt
+		if req.EndAt != nil || req.ClearEndAt {
+			endAt = req.EndAt
+		}
+		if endAt != nil && nextRunAt.After(*endAt) {
+			ctx.Error(&apperr.Error{
+				Code:    apperr.ValidationFailed,
+				Message: "request is invalid",
+				Fields:  []apperr.FieldError{{Field: "active", Message: "scheduled transfer has ended"}},
+			})
+			return
+		}
+	}
+
+	scheduled, err := s.store.UpdateScheduledTransfer(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (s *Server) deleteScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.ownedScheduledTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	err := s.store.DeleteScheduledTransfer(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "scheduled transfer deleted"})
+}
+
+// ownedScheduledTransfer loads a scheduled transfer paid from an account of
+// the authenticated user, recording the error itself when it cannot.
+func (s *Server) ownedScheduledTransfer(ctx *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduled, err := s.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		ctx.Error(err)
+		return scheduled, false
+	}
+
+	if _, ok := s.ownedAccount(ctx, scheduled.FromAccountID); !ok {
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
@@ -0,0 +1,524 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// -------------------- POST /scheduled_transfers --------------------
+func TestCreateScheduledTransfer(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	account2 := db.Account{ID: 2, Owner: "bola", Currency: "USD", Balance: 1000}
+	account3 := db.Account{ID: 3, Owner: "tola", Currency: "EUR", Balance: 1000}
+
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	scheduled := db.ScheduledTransfer{
+		ID:            1,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ScheduleUnit:  db.ScheduleUnitMonth,
+		ScheduleEvery: 1,
+		StartAt:       start,
+		NextRunAt:     start,
+		Active:        true,
+	}
+
+	tests := []struct {
+		name          string
+		body          map[string]any
+		owner         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule_unit":   "month",
+				"start_at":        start,
+			},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+
+				arg := db.CreateScheduledTransferParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        100,
+					ScheduleUnit:  db.ScheduleUnitMonth,
+					ScheduleEvery: 1,
+					StartAt:       start,
+				}
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(scheduled, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.ScheduledTransfer
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, scheduled, got)
+			},
+		},
+		{
+			name: "Forbidden_NotFromAccountOwner",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule_unit":   "month",
+				"start_at":        start,
+			},
+			owner: account2.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "Conflict_ToAccountCurrency",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account3.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule_unit":   "week",
+				"start_at":        start,
+			},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).Return(account3, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_InvalidUnit",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule_unit":   "fortnight",
+				"start_at":        start,
+			},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_EndBeforeStart",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule_unit":   "day",
+				"start_at":        start,
+				"end_at":          start.Add(-time.Hour),
+			},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			payload, _ := json.Marshal(tt.body)
+			req, err := http.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, tt.owner, time.Minute)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- /scheduled_transfers/:id --------------------
+func TestScheduledTransferByID(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	scheduled := db.ScheduledTransfer{
+		ID:            5,
+		FromAccountID: account1.ID,
+		ToAccountID:   2,
+		Amount:        100,
+		ScheduleUnit:  db.ScheduleUnitMonth,
+		ScheduleEvery: 1,
+		StartAt:       start,
+		NextRunAt:     start,
+		Active:        true,
+	}
+	paused := scheduled
+	paused.Active = false
+	ended := paused
+	endAt := start.AddDate(0, 2, 0)
+	ended.EndAt = &endAt
+
+	tests := []struct {
+		name          string
+		method        string
+		body          map[string]any
+		owner         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Get_OK",
+			method: http.MethodGet,
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(scheduled, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.ScheduledTransfer
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, scheduled, got)
+			},
+		},
+		{
+			name:   "Get_Forbidden",
+			method: http.MethodGet,
+			owner:  "bola",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(scheduled, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:   "Get_NotFound",
+			method: http.MethodGet,
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(db.ScheduledTransfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name:   "Update_Pause",
+			method: http.MethodPatch,
+			body:   map[string]any{"active": false},
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(scheduled, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+
+				arg := db.UpdateScheduledTransferParams{
+					ID:     scheduled.ID,
+					Active: sql.NullBool{Bool: false, Valid: true},
+				}
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(paused, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.ScheduledTransfer
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.False(t, got.Active)
+			},
+		},
+		{
+			name:   "Update_EndBeforeStart",
+			method: http.MethodPatch,
+			body:   map[string]any{"end_at": start.Add(-time.Hour)},
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(scheduled, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:   "Update_ClearEndAt",
+			method: http.MethodPatch,
+			body:   map[string]any{"clear_end_at": true},
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(ended, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+
+				arg := db.UpdateScheduledTransferParams{
+					ID:         scheduled.ID,
+					ClearEndAt: true,
+				}
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(paused, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:   "Update_EndAtAndClearEndAt",
+			method: http.MethodPatch,
+			body:   map[string]any{"end_at": endAt, "clear_end_at": true},
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:   "Update_ResumeSkipsMissedRuns",
+			method: http.MethodPatch,
+			body:   map[string]any{"active": true},
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(paused, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.True(t, arg.Active.Valid && arg.Active.Bool)
+						require.True(t, arg.NextRunAt.Valid)
+						require.True(t, arg.NextRunAt.Time.After(time.Now()))
+						require.True(t, arg.NextRunAt.Time.Before(time.Now().AddDate(0, 1, 1)))
+						return scheduled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:   "Update_ResumeEnded",
+			method: http.MethodPatch,
+			body:   map[string]any{"active": true},
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(ended, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:   "Delete_OK",
+			method: http.MethodDelete,
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(scheduled, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().DeleteScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:   "Delete_Forbidden",
+			method: http.MethodDelete,
+			owner:  "bola",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).Return(scheduled, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().DeleteScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			var body io.Reader = http.NoBody
+			if tt.body != nil {
+				payload, _ := json.Marshal(tt.body)
+				body = bytes.NewReader(payload)
+			}
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID)
+			req, err := http.NewRequest(tt.method, url, body)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, tt.owner, time.Minute)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- GET /accounts/:id/scheduled_transfers --------------------
+func TestListAccountScheduledTransfers(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	var scheduled []db.ScheduledTransfer
+	for i := 1; i <= 6; i++ {
+		scheduled = append(scheduled, db.ScheduledTransfer{
+			ID: int64(i), FromAccountID: account.ID, ToAccountID: 2, Amount: 10,
+			ScheduleUnit: db.ScheduleUnitWeek, ScheduleEvery: 1, StartAt: start, NextRunAt: start, Active: true,
+		})
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		owner         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK_Cursor",
+			query: "page_size=5",
+			owner: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListScheduledTransfersByAccountAfterParams{FromAccountID: account.ID, AfterID: 0, Limit: 6}
+				store.EXPECT().ListScheduledTransfersByAccountAfter(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(scheduled, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got pageResponse[db.ScheduledTransfer]
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, scheduled[:5], got.Items)
+				require.Equal(t, encodeCursor(5), got.NextCursor)
+			},
+		},
+		{
+			name:  "OK_PageID",
+			query: "page_id=1&page_size=5",
+			owner: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListScheduledTransfersByAccountParams{FromAccountID: account.ID, Limit: 5, Offset: 0}
+				store.EXPECT().ListScheduledTransfersByAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(scheduled[:5], nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.Equal(t, "true", rr.Header().Get("Deprecation"))
+			},
+		},
+		{
+			name:  "Forbidden",
+			query: "page_size=5",
+			owner: "bola",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().ListScheduledTransfersByAccountAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/scheduled_transfers?%s", account.ID, tt.query)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, tt.owner, time.Minute)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
@@ -1,32 +1,152 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
 	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
 )
 
 type Server struct {
-	store  db.Store
-	router *gin.Engine
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	fxProvider util.FXRateProvider
+	logger     *slog.Logger
+	router     *gin.Engine
+	httpServer *http.Server
 }
 
-func NewServer(store db.Store) *Server {
-	server := &Server{store: store}
-	router := gin.Default()
+// NewServer creates an HTTP server for the bank's API. Each request is
+// logged to logger; a nil logger discards the records. Request metrics are
+// registered with registry, which the API does not serve; a nil registry
+// gives the server one of its own.
+func NewServer(config util.Config, store db.Store, logger *slog.Logger, registry prometheus.Registerer) (*Server, error) {
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+	if registry == nil {
+		registry = prometheus.NewRegistry()
+	}
+
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	fxProvider, err := newFXRateProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create fx rate provider: %w", err)
+	}
+
+	server := &Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+		fxProvider: fxProvider,
+		logger:     logger,
+	}
+
+	requestDuration, err := newRequestDuration(registry)
+	if err != nil {
+		return nil, fmt.Errorf("cannot register request metrics: %w", err)
+	}
+
+	router := gin.New()
+	// Let store calls made with the gin context see the request context,
+	// which carries the request ID.
+	router.ContextWithFallback = true
+	router.Use(tracingMiddleware(otel.Tracer(tracerName), otel.GetTextMapPropagator()),
+		requestIDMiddleware(), accessLogMiddleware(logger), metricsMiddleware(requestDuration),
+		recoveryMiddleware(logger), errorMiddleware())
+	registerValidation()
+
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+
+	router.POST("/users", server.createUser)
+	router.POST("/users/login", server.loginUser)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
 
-	router.POST("/accounts", server.createAccount)
-	router.GET("/accounts/:id", server.getAccountByID)
-	router.GET("/accounts/", server.getAllAccounts)
-	router.PATCH("/accounts/:id", server.updateAccount)
-	router.DELETE("/accounts/:id", server.deleteAccount)
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+
+	authRoutes.DELETE("/users/:username/sessions", server.revokeUserSessions)
+
+	authRoutes.POST("/accounts", server.createAccount)
+	authRoutes.GET("/accounts/:id", server.getAccountByID)
+	authRoutes.GET("/accounts/", server.getAllAccounts)
+	authRoutes.PATCH("/accounts/:id", roleMiddleware(server.store, util.AdminRole), server.updateAccount)
+	authRoutes.DELETE("/accounts/:id", server.deleteAccount)
+	authRoutes.POST("/accounts/:id/deposits", roleMiddleware(server.store, util.AdminRole), server.createDeposit)
+	authRoutes.POST("/accounts/:id/withdrawals", server.createWithdrawal)
+	authRoutes.GET("/accounts/:id/entries", server.listAccountEntries)
+	authRoutes.GET("/accounts/:id/transfers", server.listAccountTransfers)
+	authRoutes.GET("/accounts/:id/scheduled_transfers", server.listAccountScheduledTransfers)
+
+	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/transfers/batch", server.createBatchTransfer)
+	authRoutes.POST("/transfers/authorizations", server.authorizeTransfer)
+	authRoutes.GET("/transfers/:id", server.getTransfer)
+	authRoutes.POST("/transfers/:id/reversals", server.reverseTransfer)
+	authRoutes.POST("/transfers/:id/capture", server.captureTransfer)
+	authRoutes.POST("/transfers/:id/void", server.voidTransfer)
+
+	authRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.deleteScheduledTransfer)
 	server.router = router
-	return server
+	server.httpServer = &http.Server{
+		Handler:           router,
+		ReadHeaderTimeout: config.HTTPReadHeaderTimeout,
+		ReadTimeout:       config.HTTPReadTimeout,
+		WriteTimeout:      config.HTTPWriteTimeout,
+		IdleTimeout:       config.HTTPIdleTimeout,
+		MaxHeaderBytes:    config.HTTPMaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	return server, nil
 }
 
+// newFXRateProvider loads the configured rates file. Without one, only
+// same-currency transfers can be quoted.
+func newFXRateProvider(config util.Config) (util.FXRateProvider, error) {
+	if config.FXRatesFile == "" {
+		return util.NewStaticFXRateProvider(nil)
+	}
+	return util.LoadFXRatesFile(config.FXRatesFile)
+}
+
+// Start listens on address and serves requests until Shutdown is called. It
+// returns nil once the server was shut down.
 func (s *Server) Start(address string) error {
-	return s.router.Run(address)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves requests on listener until Shutdown is called
+func (s *Server) Serve(listener net.Listener) error {
+	err := s.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish, or for ctx to be done, whichever comes first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServerInvalidTokenKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(16),
+		AccessTokenDuration: time.Minute,
+	}
+
+	server, err := NewServer(config, store, nil, nil)
+	require.Error(t, err)
+	require.Nil(t, server)
+}
+
+func TestServerShutdownDrainsRequests(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server.router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	type result struct {
+		rsp *http.Response
+		err error
+	}
+	responded := make(chan result, 1)
+	go func() {
+		rsp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err == nil {
+			rsp.Body.Close()
+		}
+		responded <- result{rsp, err}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned before the request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-responded
+	require.NoError(t, res.err)
+	require.Equal(t, http.StatusOK, res.rsp.StatusCode)
+	require.NoError(t, <-shutdown)
+	require.NoError(t, <-served)
+
+	_, err = http.Get("http://" + listener.Addr().String() + "/slow")
+	require.Error(t, err)
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server.router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		<-release
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	go http.Get("http://" + listener.Addr().String() + "/slow")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	config := util.Config{TokenSymmetricKey: util.RandomString(32)}
+	server, err := NewServer(config, nil, nil, registry)
+	require.NoError(t, err)
+
+	for _, url := range []string{"/healthz", "/healthz", "/no/such/route"} {
+		server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+	require.Contains(t, body, `simple_bank_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"} 2`)
+	require.Contains(t, body, `simple_bank_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	require.NotContains(t, body, "/no/such/route")
+
+	// the metrics are served on their own address, not by the API
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
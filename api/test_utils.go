@@ -2,13 +2,33 @@
 package api
 
 import (
+	"testing"
+	"time"
+
 	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/stretchr/testify/require"
 
 	"github.com/gin-gonic/gin"
 )
 
 func init() { gin.SetMode(gin.TestMode) }
 
-func newTestServer(store db.Store) *Server {
-	return NewServer(store)
+// testSchemaVersion is the migration test servers expect the database at
+const testSchemaVersion = 11
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenSymmetricKey:       util.RandomString(32),
+		AccessTokenDuration:     time.Minute,
+		RefreshTokenDuration:    time.Hour,
+		IdempotencyKeyRetention: time.Hour,
+		HoldDuration:            24 * time.Hour,
+		SchemaVersion:           testSchemaVersion,
+	}
+
+	server, err := NewServer(config, store, nil, nil)
+	require.NoError(t, err)
+
+	return server
 }
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (s *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefresh)
+	if err != nil {
+		ctx.Error(apperr.Wrap(err, apperr.Unauthorized, "invalid refresh token"))
+		return
+	}
+
+	// A refresh token whose session is gone, e.g. after it was revoked, is
+	// rejected like any other invalid credential.
+	session, err := s.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if apperr.CodeOf(err) == apperr.NotFound {
+			err = apperr.Wrap(err, apperr.Unauthorized, "invalid session")
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if session.IsBlocked {
+		ctx.Error(apperr.New(apperr.Unauthorized, "blocked session"))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		ctx.Error(apperr.New(apperr.Unauthorized, "incorrect session user"))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		ctx.Error(apperr.New(apperr.Unauthorized, "mismatched session token"))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		ctx.Error(apperr.New(apperr.Unauthorized, "expired session"))
+		return
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(refreshPayload.Username, token.TokenTypeAccess, s.config.AccessTokenDuration)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
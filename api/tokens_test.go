@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// -------------------- POST /tokens/renew_access --------------------
+func TestRenewAccessToken(t *testing.T) {
+	username := "fola"
+
+	tests := []struct {
+		name          string
+		tokenType     token.TokenType
+		duration      time.Duration
+		buildStubs    func(store *mockdb.MockStore, refreshToken string, payload *token.Payload)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).Return(db.Session{
+					ID:           payload.ID,
+					Username:     username,
+					RefreshToken: refreshToken,
+					ExpiresAt:    payload.ExpiredAt,
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+
+				var got renewAccessTokenResponse
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+				require.NotEmpty(t, got.AccessToken)
+				require.NotZero(t, got.AccessTokenExpiresAt)
+			},
+		},
+		{
+			name:      "ExpiredRefreshToken",
+			tokenType: token.TokenTypeRefresh,
+			duration:  -time.Minute,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:      "AccessToken",
+			tokenType: token.TokenTypeAccess,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:      "SessionNotFound",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).Return(db.Session{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.Contains(t, rr.Body.String(), "invalid session")
+			},
+		},
+		{
+			name:      "BlockedSession",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).Return(db.Session{
+					ID:           payload.ID,
+					Username:     username,
+					RefreshToken: refreshToken,
+					IsBlocked:    true,
+					ExpiresAt:    payload.ExpiredAt,
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:      "IncorrectSessionUser",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).Return(db.Session{
+					ID:           payload.ID,
+					Username:     "bola",
+					RefreshToken: refreshToken,
+					ExpiresAt:    payload.ExpiredAt,
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:      "MismatchedSessionToken",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).Return(db.Session{
+					ID:           payload.ID,
+					Username:     username,
+					RefreshToken: "another-token",
+					ExpiresAt:    payload.ExpiredAt,
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:      "ExpiredSession",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).Return(db.Session{
+					ID:           payload.ID,
+					Username:     username,
+					RefreshToken: refreshToken,
+					ExpiresAt:    time.Now().Add(-time.Minute),
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:      "InternalError_DB",
+			tokenType: token.TokenTypeRefresh,
+			duration:  time.Hour,
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			server := newTestServer(t, store)
+			refreshToken, payload, err := server.tokenMaker.CreateToken(username, tt.tokenType, tt.duration)
+			require.NoError(t, err)
+			tt.buildStubs(store, refreshToken, payload)
+
+			rr := httptest.NewRecorder()
+
+			body, _ := json.Marshal(map[string]any{"refresh_token": refreshToken})
+			req, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
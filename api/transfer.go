@@ -0,0 +1,455 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type createTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+}
+
+func (s *Server) createTransfer(ctx *gin.Context) {
+	var req createTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > 255 {
+		err := fmt.Errorf("%s header must be at most 255 characters", idempotencyKeyHeader)
+		ctx.Error(validationError(err))
+		return
+	}
+
+	quote, ok := s.quoteTransfer(ctx, req)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.TransferTxParams{
+		FromAccountID:       req.FromAccountID,
+		ToAccountID:         req.ToAccountID,
+		Amount:              req.Amount,
+		IdempotencyKey:      idempotencyKey,
+		IdempotencyKeyOwner: authPayload.Username,
+		IdempotencyKeyTTL:   s.config.IdempotencyKeyRetention,
+		Quote:               quote,
+	}
+
+	result, err := s.store.TransferTx(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+const batchModeAllOrNothing = "all_or_nothing"
+
+type batchTransferItem struct {
+	ToAccountID int64 `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64 `json:"amount" binding:"required,gt=0"`
+}
+
+type batchTransferRequest struct {
+	FromAccountID int64               `json:"from_account_id" binding:"required,min=1"`
+	Currency      string              `json:"currency" binding:"required,currency"`
+	Mode          string              `json:"mode" binding:"required,oneof=all_or_nothing best_effort"`
+	Items         []batchTransferItem `json:"items" binding:"required,min=1,max=1000,dive"`
+}
+
+// createBatchTransfer pays many accounts from one funding account in a
+// single transaction. In all_or_nothing mode any failed item cancels the
+// batch; in best_effort mode failed items are reported alongside the ones
+// that went through. Every destination must hold the batch's currency.
+func (s *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	fromAccount, ok := s.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.Error(errAccountNotOwned)
+		return
+	}
+
+	arg := db.BatchTransferTxParams{
+		FromAccountID: req.FromAccountID,
+		Items:         make([]db.BatchTransferItem, len(req.Items)),
+		AllOrNothing:  req.Mode == batchModeAllOrNothing,
+	}
+	for i, item := range req.Items {
+		arg.Items[i] = db.BatchTransferItem{
+			ToAccountID: item.ToAccountID,
+			Amount:      item.Amount,
+		}
+	}
+
+	result, err := s.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// quoteTransfer checks the caller may send the transfer and quotes the rate
+// at which the destination is credited, recording the error itself when it
+// cannot.
+func (s *Server) quoteTransfer(ctx *gin.Context, req createTransferRequest) (util.FXQuote, bool) {
+	fromAccount, ok := s.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !ok {
+		return util.FXQuote{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.Error(errAccountNotOwned)
+		return util.FXQuote{}, false
+	}
+
+	toAccount, ok := s.getAccount(ctx, req.ToAccountID)
+	if !ok {
+		return util.FXQuote{}, false
+	}
+
+	// The destination may hold another currency; it is credited at the
+	// provider's current rate.
+	quote, err := s.fxProvider.Quote(ctx, fromAccount.Currency, toAccount.Currency)
+	if err != nil {
+		ctx.Error(err)
+		return quote, false
+	}
+
+	return quote, true
+}
+
+// authorizeTransfer puts the amount of a transfer on hold without moving
+// it. The transfer settles when captured, and fails when voided or when the
+// hold expires.
+func (s *Server) authorizeTransfer(ctx *gin.Context) {
+	var req createTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	quote, ok := s.quoteTransfer(ctx, req)
+	if !ok {
+		return
+	}
+
+	arg := db.AuthorizeTransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Quote:         quote,
+		HoldDuration:  s.config.HoldDuration,
+	}
+
+	result, err := s.store.AuthorizeTransferTx(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type authorizedTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// captureTransfer settles an authorized transfer. Only the owner of the
+// sending account can capture it.
+func (s *Server) captureTransfer(ctx *gin.Context) {
+	var uri authorizedTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.sentTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	result, err := s.store.CaptureTransferTx(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// voidTransfer cancels an authorized transfer and releases its hold. Only
+// the owner of the sending account can void it.
+func (s *Server) voidTransfer(ctx *gin.Context) {
+	var uri authorizedTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.sentTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	result, err := s.store.VoidTransferTx(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// sentTransfer loads a transfer sent from an account of the authenticated
+// user, recording the error itself when it cannot.
+func (s *Server) sentTransfer(ctx *gin.Context, transferID int64) (db.Transfer, bool) {
+	transfer, err := s.store.GetTransfer(ctx, transferID)
+	if err != nil {
+		ctx.Error(err)
+		return transfer, false
+	}
+
+	fromAccount, ok := s.getAccount(ctx, transfer.FromAccountID)
+	if !ok {
+		return transfer, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.Error(errAccountNotOwned)
+		return transfer, false
+	}
+
+	return transfer, true
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getTransfer returns a transfer to the owner of either of its accounts.
+func (s *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+		account, ok := s.getAccount(ctx, accountID)
+		if !ok {
+			return
+		}
+		if account.Owner == authPayload.Username {
+			ctx.JSON(http.StatusOK, transfer)
+			return
+		}
+	}
+
+	ctx.Error(apperr.New(apperr.Forbidden, "transfer doesn't involve an account of the authenticated user"))
+}
+
+type reverseTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// Amount to refund; omit it to refund whatever hasn't been refunded yet
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// reverseTransfer refunds a transfer back to its sender. Only the owner of
+// the receiving account can give the money back.
+func (s *Server) reverseTransfer(ctx *gin.Context) {
+	var uri reverseTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	toAccount, ok := s.getAccount(ctx, transfer.ToAccountID)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username {
+		ctx.Error(errAccountNotOwned)
+		return
+	}
+
+	result, err := s.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: uri.ID,
+		Amount:     req.Amount,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+const (
+	transferDirectionIncoming = "incoming"
+	transferDirectionOutgoing = "outgoing"
+)
+
+type listAccountTransfersURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listAccountTransfersRequest struct {
+	Direction string `form:"direction" binding:"omitempty,oneof=incoming outgoing both"`
+	pageRequest
+	timeRangeQuery
+}
+
+func (s *Server) listAccountTransfers(ctx *gin.Context) {
+	var uri listAccountTransfersURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	var req listAccountTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	if _, ok := s.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	var fromAccountID, toAccountID sql.NullInt64
+	accountID := sql.NullInt64{Int64: uri.ID, Valid: true}
+	switch req.Direction {
+	case transferDirectionIncoming:
+		toAccountID = accountID
+	case transferDirectionOutgoing:
+		fromAccountID = accountID
+	default: // both directions
+		fromAccountID = accountID
+		toAccountID = accountID
+	}
+
+	start, end := req.bounds()
+	if req.usesOffset(ctx) {
+		arg := db.ListTransfersByAccountParams{
+			FromAccountID: fromAccountID,
+			ToAccountID:   toAccountID,
+			StartTime:     start,
+			EndTime:       end,
+			Limit:         req.PageSize,
+			Offset:        req.offset(),
+		}
+		transfers, err := s.store.ListTransfersByAccount(ctx, arg)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, transfers)
+		return
+	}
+
+	afterID, err := req.afterID()
+	if err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	arg := db.ListTransfersByAccountAfterParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		AfterID:       afterID,
+		StartTime:     start,
+		EndTime:       end,
+		Limit:         req.limit(),
+	}
+	transfers, err := s.store.ListTransfersByAccountAfter(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(transfers, req.PageSize, func(t db.Transfer) int64 { return t.ID }))
+}
+
+// validAccount checks that the account exists and holds the given currency,
+// recording the error itself when it does not.
+func (s *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, ok := s.getAccount(ctx, accountID)
+	if !ok {
+		return account, false
+	}
+
+	if account.Currency != currency {
+		err := fmt.Errorf("%w: account [%d] holds %s, not %s", db.ErrCurrencyMismatch, account.ID, account.Currency, currency)
+		ctx.Error(err)
+		return account, false
+	}
+
+	return account, true
+}
+
+// getAccount loads the account, recording the error itself when it cannot.
+func (s *Server) getAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		ctx.Error(err)
+		return account, false
+	}
+
+	return account, true
+}
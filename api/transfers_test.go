@@ -0,0 +1,1439 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// -------------------- helpers --------------------
+func decodeTransferTxResult(t *testing.T, b *bytes.Buffer) db.TransferTxResult {
+	t.Helper()
+	var result db.TransferTxResult
+	require.NoError(t, json.NewDecoder(bytes.NewReader(b.Bytes())).Decode(&result))
+	return result
+}
+
+// -------------------- POST /transfers --------------------
+func TestCreateTransfer(t *testing.T) {
+	amount := int64(10)
+
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	account2 := db.Account{ID: 2, Owner: "bola", Currency: "USD", Balance: 1000}
+	account3 := db.Account{ID: 3, Owner: "tola", Currency: "EUR", Balance: 1000}
+	account4 := db.Account{ID: 4, Owner: "lola", Currency: "CAD", Balance: 1000}
+
+	result := db.TransferTxResult{
+		Transfer:    db.Transfer{ID: 1, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: amount},
+		FromAccount: db.Account{ID: account1.ID, Owner: account1.Owner, Currency: "USD", Balance: account1.Balance - amount},
+		ToAccount:   db.Account{ID: account2.ID, Owner: account2.Owner, Currency: "USD", Balance: account2.Balance + amount},
+		FromEntry:   db.Entry{ID: 1, AccountID: account1.ID, Amount: -amount},
+		ToEntry:     db.Entry{ID: 2, AccountID: account2.ID, Amount: amount},
+	}
+
+	fxProvider, err := util.NewStaticFXRateProvider(map[string]string{"USD/EUR": "0.9"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           map[string]any
+		idempotencyKey string
+		setupAuth      func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs     func(store *mockdb.MockStore)
+		checkResponse  func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:       account1.ID,
+					ToAccountID:         account2.ID,
+					Amount:              amount,
+					IdempotencyKeyOwner: account1.Owner,
+					IdempotencyKeyTTL:   time.Hour,
+					Quote:               util.FXQuote{From: "USD", To: "USD", Rate: "1"},
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				got := decodeTransferTxResult(t, rr.Body)
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "Forbidden_NotFromAccountOwner",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "Unauthorized_NoToken",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "FromAccountNotFound",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name: "ToAccountNotFound",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name: "FromAccountCurrencyMismatch",
+			body: map[string]any{
+				"from_account_id": account3.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).Return(account3, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rr.Code)
+			},
+		},
+		{
+			name: "OK_CrossCurrency",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account3.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).
+					Times(1).Return(account3, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:       account1.ID,
+					ToAccountID:         account3.ID,
+					Amount:              amount,
+					IdempotencyKeyOwner: account1.Owner,
+					IdempotencyKeyTTL:   time.Hour,
+					Quote:               util.FXQuote{From: "USD", To: "EUR", Rate: "0.9"},
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "UnsupportedCurrencyPair",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account4.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account4.ID)).
+					Times(1).Return(account4, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.TransferTxResult{}, db.ErrCurrencyMismatch)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_InvalidCurrency",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "NGN",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_NegativeAmount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          -amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_SameAccount",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account1.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "GetAccountError",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.Account{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.TransferTxResult{}, &db.InsufficientFundsError{
+					AccountID: account1.ID,
+					Available: 5,
+					Requested: amount,
+				})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+				var body struct {
+					Code             string `json:"code"`
+					Detail           string `json:"detail"`
+					AvailableBalance int64  `json:"available_balance"`
+				}
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+				require.Equal(t, int64(5), body.AvailableBalance)
+				require.Equal(t, "insufficient_funds", body.Code)
+				require.NotEmpty(t, body.Detail)
+			},
+		},
+		{
+			name: "OK_WithIdempotencyKey",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			idempotencyKey: "4f1c2f8e-retry-key",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:       account1.ID,
+					ToAccountID:         account2.ID,
+					Amount:              amount,
+					IdempotencyKey:      "4f1c2f8e-retry-key",
+					IdempotencyKeyOwner: account1.Owner,
+					IdempotencyKeyTTL:   time.Hour,
+					Quote:               util.FXQuote{From: "USD", To: "USD", Rate: "1"},
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				got := decodeTransferTxResult(t, rr.Body)
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "IdempotencyKeyReused",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			idempotencyKey: "4f1c2f8e-retry-key",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.TransferTxResult{}, db.ErrIdempotencyKeyReused)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_IdempotencyKeyTooLong",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			idempotencyKey: strings.Repeat("k", 256),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "TransferTxError",
+			body: map[string]any{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        "USD",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.TransferTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.fxProvider = fxProvider
+			rr := httptest.NewRecorder()
+
+			payload, _ := json.Marshal(tt.body)
+			req, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if tt.idempotencyKey != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.idempotencyKey)
+			}
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- POST /transfers/batch --------------------
+func TestCreateBatchTransfer(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+
+	items := []map[string]any{
+		{"to_account_id": 2, "amount": 100},
+		{"to_account_id": 3, "amount": 200},
+	}
+	arg := db.BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []db.BatchTransferItem{
+			{ToAccountID: 2, Amount: 100},
+			{ToAccountID: 3, Amount: 200},
+		},
+	}
+
+	transfer := db.Transfer{ID: 9, FromAccountID: account1.ID, ToAccountID: 2, Amount: 100, ToAmount: 100,
+		ExchangeRate: "1", Status: db.TransferStatusSettled}
+	result := db.BatchTransferTxResult{
+		FromAccount: db.Account{ID: account1.ID, Owner: account1.Owner, Currency: "USD", Balance: 900},
+		Items: []db.BatchTransferItemResult{
+			{Transfer: &transfer},
+			{Error: "account 3: sql: no rows in result set"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		body          map[string]any
+		owner         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK_BestEffort",
+			body:  map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "best_effort", "items": items},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.BatchTransferTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name:  "AllOrNothing_ItemFails",
+			body:  map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "all_or_nothing", "items": items},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+
+				allOrNothing := arg
+				allOrNothing.AllOrNothing = true
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Eq(allOrNothing)).
+					Times(1).Return(db.BatchTransferTxResult{}, &db.BatchItemError{
+					Index: 1,
+					Err:   &db.InsufficientFundsError{AccountID: account1.ID, Available: 150, Requested: 200},
+				})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+				var body map[string]any
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, float64(1), body["index"])
+				require.Equal(t, float64(150), body["available_balance"])
+			},
+		},
+		{
+			name:  "Forbidden_NotFundingAccountOwner",
+			body:  map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "best_effort", "items": items},
+			owner: "bola",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:  "BadRequest_InvalidMode",
+			body:  map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "sometimes", "items": items},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_InvalidItem",
+			body: map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "best_effort",
+				"items": []map[string]any{{"to_account_id": 2, "amount": -5}}},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:  "BadRequest_NoItems",
+			body:  map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "best_effort", "items": []any{}},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			body:  map[string]any{"from_account_id": account1.ID, "currency": "USD", "mode": "best_effort", "items": items},
+			owner: account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.BatchTransferTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			payload, _ := json.Marshal(tt.body)
+			req, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, tt.owner, time.Minute)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- GET /transfers/:id --------------------
+func TestGetTransfer(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	account2 := db.Account{ID: 2, Owner: "bola", Currency: "USD", Balance: 1000}
+	transfer := db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, ToAmount: 10, ExchangeRate: "1"}
+
+	tests := []struct {
+		name          string
+		transferID    int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK_Sender",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.Transfer
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, transfer, got)
+			},
+		},
+		{
+			name:       "OK_Recipient",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:       "Forbidden_NotParty",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "someoneelse", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(db.Transfer{}, db.ErrRecordNotFound)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name:       "InternalError",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(db.Transfer{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+		{
+			name:       "BadRequest_InvalidID",
+			transferID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d", tt.transferID)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- GET /accounts/:id/transfers --------------------
+func TestListAccountTransfers(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	transfers := []db.Transfer{
+		{ID: 1, FromAccountID: account.ID, ToAccountID: 2, Amount: 10, ToAmount: 10, ExchangeRate: "1"},
+		{ID: 2, FromAccountID: 2, ToAccountID: account.ID, Amount: 20, ToAmount: 20, ExchangeRate: "1"},
+	}
+	accountID := sql.NullInt64{Int64: account.ID, Valid: true}
+
+	tests := []struct {
+		name          string
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK_Both",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListTransfersByAccountParams{FromAccountID: accountID, ToAccountID: accountID, Limit: 5, Offset: 0}
+				store.EXPECT().ListTransfersByAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got []db.Transfer
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, transfers, got)
+			},
+		},
+		{
+			name:  "OK_Incoming",
+			query: "page_id=2&page_size=5&direction=incoming",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListTransfersByAccountParams{ToAccountID: accountID, Limit: 5, Offset: 5}
+				store.EXPECT().ListTransfersByAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(transfers[1:], nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:  "OK_Outgoing",
+			query: "page_id=1&page_size=5&direction=outgoing",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListTransfersByAccountParams{FromAccountID: accountID, Limit: 5, Offset: 0}
+				store.EXPECT().ListTransfersByAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(transfers[:1], nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name:  "OK_Cursor",
+			query: "page_size=5&direction=outgoing",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				arg := db.ListTransfersByAccountAfterParams{FromAccountID: accountID, AfterID: 0, Limit: 6}
+				store.EXPECT().ListTransfersByAccountAfter(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(transfers[:1], nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got pageResponse[db.Transfer]
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, transfers[:1], got.Items)
+				require.Empty(t, got.NextCursor)
+			},
+		},
+		{
+			name:  "BadRequest_InvalidDirection",
+			query: "page_id=1&page_size=5&direction=sideways",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransfersByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name:  "Forbidden_NotOwner",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "someoneelse", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().ListTransfersByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:  "InternalError_DB",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).Return(account, nil)
+				store.EXPECT().ListTransfersByAccount(gomock.Any(), gomock.Any()).
+					Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/transfers?%s", account.ID, tt.query)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- POST /transfers/:id/reversals --------------------
+func TestReverseTransfer(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	account2 := db.Account{ID: 2, Owner: "bola", Currency: "USD", Balance: 1000}
+	transfer := db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 100, ToAmount: 100, ExchangeRate: "1"}
+
+	result := db.ReverseTransferTxResult{
+		OriginalTransfer: db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 100, ToAmount: 100,
+			ExchangeRate: "1", Status: db.TransferStatusPartiallyReversed},
+		TransferTxResult: db.TransferTxResult{
+			Transfer: db.Transfer{ID: 8, FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 40, ToAmount: 40,
+				ExchangeRate: "1", ReversalOf: &transfer.ID},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		body          map[string]any
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK_Partial",
+			body: map[string]any{"amount": 40},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID, Amount: 40}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.ReverseTransferTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "OK_FullWithoutBody",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "Forbidden_Sender",
+			body: map[string]any{"amount": 40},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: map[string]any{"amount": 40},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(db.Transfer{}, db.ErrRecordNotFound)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name: "BadRequest_NegativeAmount",
+			body: map[string]any{"amount": -1},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "ExceedsRemaining",
+			body: map[string]any{"amount": 500},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.ReverseTransferTxResult{}, db.ErrInvalidReversalAmount)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+		{
+			name: "AlreadyReversed",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.ReverseTransferTxResult{}, db.ErrTransferNotReversible)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.ReverseTransferTxResult{}, &db.InsufficientFundsError{
+					AccountID: account2.ID,
+					Available: 10,
+					Requested: 100,
+				})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			var body io.Reader = http.NoBody
+			if tt.body != nil {
+				payload, _ := json.Marshal(tt.body)
+				body = bytes.NewReader(payload)
+			}
+
+			url := fmt.Sprintf("/transfers/%d/reversals", transfer.ID)
+			req, err := http.NewRequest(http.MethodPost, url, body)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- POST /transfers/authorizations --------------------
+func TestAuthorizeTransfer(t *testing.T) {
+	amount := int64(10)
+
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000, AvailableBalance: 1000}
+	account2 := db.Account{ID: 2, Owner: "bola", Currency: "USD", Balance: 1000, AvailableBalance: 1000}
+
+	result := db.HoldTxResult{
+		Transfer: db.Transfer{ID: 1, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: amount,
+			ToAmount: amount, ExchangeRate: "1", Status: db.TransferStatusAuthorized},
+		Hold: db.Hold{ID: 1, AccountID: account1.ID, TransferID: 1, Amount: amount},
+		FromAccount: db.Account{ID: account1.ID, Owner: account1.Owner, Currency: "USD", Balance: account1.Balance,
+			HeldBalance: amount, AvailableBalance: account1.Balance - amount},
+	}
+
+	body := map[string]any{
+		"from_account_id": account1.ID,
+		"to_account_id":   account2.ID,
+		"amount":          amount,
+		"currency":        "USD",
+	}
+
+	tests := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+
+				arg := db.AuthorizeTransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        amount,
+					Quote:         util.FXQuote{From: "USD", To: "USD", Rate: "1"},
+					HoldDuration:  24 * time.Hour,
+				}
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.HoldTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, result, got)
+			},
+		},
+		{
+			name: "Forbidden_NotFromAccountOwner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account2.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.HoldTxResult{}, &db.InsufficientFundsError{
+					AccountID: account1.ID,
+					Available: 5,
+					Requested: amount,
+				})
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+				var body map[string]any
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, float64(5), body["available_balance"])
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+					Times(1).Return(account2, nil)
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.HoldTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			payload, _ := json.Marshal(body)
+			req, err := http.NewRequest(http.MethodPost, "/transfers/authorizations", bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			tt.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
+
+// -------------------- POST /transfers/:id/capture and /void --------------------
+func TestSettleAuthorizedTransfer(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: "fola", Currency: "USD", Balance: 1000}
+	transfer := db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: 2, Amount: 100, ToAmount: 100,
+		ExchangeRate: "1", Status: db.TransferStatusAuthorized}
+
+	captured := db.TransferTxResult{
+		Transfer: db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: 2, Amount: 100, ToAmount: 100,
+			ExchangeRate: "1", Status: db.TransferStatusSettled},
+	}
+	voided := db.HoldTxResult{
+		Transfer: db.Transfer{ID: 7, FromAccountID: account1.ID, ToAccountID: 2, Amount: 100, ToAmount: 100,
+			ExchangeRate: "1", Status: db.TransferStatusFailed},
+	}
+
+	tests := []struct {
+		name          string
+		action        string
+		owner         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Capture_OK",
+			action: "capture",
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().CaptureTransferTx(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(captured, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.Equal(t, captured, decodeTransferTxResult(t, rr.Body))
+			},
+		},
+		{
+			name:   "Capture_HoldExpired",
+			action: "capture",
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().CaptureTransferTx(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(db.TransferTxResult{}, db.ErrHoldExpired)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+		{
+			name:   "Capture_Forbidden",
+			action: "capture",
+			owner:  "bola",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().CaptureTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name:   "Capture_NotFound",
+			action: "capture",
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(db.Transfer{}, db.ErrRecordNotFound)
+				store.EXPECT().CaptureTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+		{
+			name:   "Void_OK",
+			action: "void",
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(voided, nil)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				var got db.HoldTxResult
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, voided, got)
+			},
+		},
+		{
+			name:   "Void_NotAuthorized",
+			action: "void",
+			owner:  account1.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).Return(account1, nil)
+				store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).Return(db.HoldTxResult{}, db.ErrTransferNotAuthorized)
+			},
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tt.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rr := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d/%s", transfer.ID, tt.action)
+			req, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, tt.owner, time.Minute)
+			server.router.ServeHTTP(rr, req)
+			tt.checkResponse(t, rr)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/token"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type createUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+	FullName string `json:"full_name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+}
+
+// userResponse is the public view of a user; it never carries the password hash.
+type userResponse struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newUserResponse(user db.User) userResponse {
+	return userResponse{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	}
+}
+
+func (s *Server) createUser(ctx *gin.Context) {
+	var req createUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	arg := db.CreateUserParams{
+		Username:       req.Username,
+		HashedPassword: hashedPassword,
+		FullName:       req.FullName,
+		Email:          req.Email,
+	}
+
+	user, err := s.store.CreateUser(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// errIncorrectCredentials is the message of every rejected login
+const errIncorrectCredentials = "incorrect username or password"
+
+// dummyPasswordHash is checked against when the username is unknown, so that
+// a login takes as long whether or not the user exists.
+const dummyPasswordHash = "$2a$10$TUduJCor3jEVsbXTGCqzs.RZtpO2wJMnMeVxnTeKeUQDqtX7FCCF2"
+
+type loginUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type loginUserResponse struct {
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
+}
+
+func (s *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	// An unknown username gets the same error as a wrong password, after
+	// the same password check, so the endpoint cannot be used to find out
+	// which usernames exist.
+	user, err := s.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if apperr.CodeOf(err) == apperr.NotFound {
+			_ = util.CheckPassword(req.Password, dummyPasswordHash)
+			err = apperr.Wrap(err, apperr.Unauthorized, errIncorrectCredentials)
+		}
+		ctx.Error(err)
+		return
+	}
+
+	err = util.CheckPassword(req.Password, user.HashedPassword)
+	if err != nil {
+		ctx.Error(apperr.Wrap(err, apperr.Unauthorized, errIncorrectCredentials))
+		return
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, token.TokenTypeAccess, s.config.AccessTokenDuration)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(user.Username, token.TokenTypeRefresh, s.config.RefreshTokenDuration)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	rsp := loginUserResponse{
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeUserSessionsRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// revokeUserSessions blocks every session of the user so none of their
+// refresh tokens can be used to renew an access token again.
+func (s *Server) revokeUserSessions(ctx *gin.Context) {
+	var req revokeUserSessionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(validationError(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.Username != authPayload.Username {
+		ctx.Error(apperr.New(apperr.Forbidden, "cannot revoke sessions of another user"))
+		return
+	}
+
+	revoked, err := s.store.BlockUserSessions(ctx, req.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"revoked_sessions": revoked})
+}
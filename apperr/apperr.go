@@ -0,0 +1,146 @@
+// Package apperr defines the errors the bank reports to its clients. Each
+// carries a stable code clients can switch on, independent of the message
+// and of the layer the error came from.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Code identifies a kind of failure
+type Code string
+
+const (
+	NotFound          Code = "not_found"
+	Conflict          Code = "conflict"
+	InsufficientFunds Code = "insufficient_funds"
+	CurrencyMismatch  Code = "currency_mismatch"
+	ValidationFailed  Code = "validation_failed"
+	Forbidden         Code = "forbidden"
+	Unauthorized      Code = "unauthorized"
+	// InvalidArgument means the arguments of a call can never be valid
+	// together, whatever the state of the resources they name.
+	InvalidArgument Code = "invalid_argument"
+	// Unprocessable means the request is well formed but the current state
+	// of the resources it names doesn't allow it.
+	Unprocessable Code = "unprocessable"
+	Internal      Code = "internal"
+)
+
+// Status returns the HTTP status a failure of this kind is reported with
+func (c Code) Status() int {
+	switch c {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict, CurrencyMismatch:
+		return http.StatusConflict
+	case InsufficientFunds, Unprocessable:
+		return http.StatusUnprocessableEntity
+	case ValidationFailed, InvalidArgument:
+		return http.StatusBadRequest
+	case Forbidden:
+		return http.StatusForbidden
+	case Unauthorized:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FieldError describes why one field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a failure with a code. Message is safe to show to clients; Err,
+// the underlying cause, is not.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// New creates an error with the given code and client-facing message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an error with the given code and message caused by err
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// As returns the first *Error in err's chain
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or Internal
+// when there is none.
+func CodeOf(err error) Code {
+	if appErr, ok := As(err); ok {
+		return appErr.Code
+	}
+	return Internal
+}
+
+// Extender is implemented by errors that carry details clients may act on,
+// such as the balance still available after a rejected withdrawal.
+type Extender interface {
+	Extensions() map[string]any
+}
+
+// ExtensionsOf merges the details of every Extender in err's chain. Errors
+// closer to the top of the chain win when keys clash.
+func ExtensionsOf(err error) map[string]any {
+	var chain []Extender
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ext, ok := e.(Extender); ok {
+			chain = append(chain, ext)
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+
+	extensions := make(map[string]any)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].Extensions() {
+			extensions[k] = v
+		}
+	}
+	return extensions
+}
+
+// Detail returns err's message for clients: the message of its *Error, with
+// any context wrapped around it but without its cause, which may hold
+// internals such as database messages. Errors with no *Error in their chain
+// get a generic message.
+func Detail(err error) string {
+	appErr, ok := As(err)
+	if !ok {
+		return "internal server error"
+	}
+	if appErr.Err == nil {
+		return err.Error()
+	}
+	return strings.Replace(err.Error(), appErr.Error(), appErr.Message, 1)
+}
@@ -0,0 +1,90 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type detailedError struct {
+	err     error
+	details map[string]any
+}
+
+func (e *detailedError) Error() string              { return e.err.Error() }
+func (e *detailedError) Unwrap() error              { return e.err }
+func (e *detailedError) Extensions() map[string]any { return e.details }
+
+func TestCodeStatus(t *testing.T) {
+	require.Equal(t, http.StatusNotFound, NotFound.Status())
+	require.Equal(t, http.StatusConflict, Conflict.Status())
+	require.Equal(t, http.StatusUnprocessableEntity, InsufficientFunds.Status())
+	require.Equal(t, http.StatusConflict, CurrencyMismatch.Status())
+	require.Equal(t, http.StatusBadRequest, ValidationFailed.Status())
+	require.Equal(t, http.StatusBadRequest, InvalidArgument.Status())
+	require.Equal(t, http.StatusForbidden, Forbidden.Status())
+	require.Equal(t, http.StatusUnauthorized, Unauthorized.Status())
+	require.Equal(t, http.StatusUnprocessableEntity, Unprocessable.Status())
+	require.Equal(t, http.StatusInternalServerError, Internal.Status())
+	require.Equal(t, http.StatusInternalServerError, Code("unknown").Status())
+}
+
+func TestCodeOf(t *testing.T) {
+	errGone := New(NotFound, "gone")
+
+	require.Equal(t, NotFound, CodeOf(errGone))
+	require.Equal(t, NotFound, CodeOf(fmt.Errorf("account 7: %w", errGone)))
+	require.Equal(t, Internal, CodeOf(errors.New("boom")))
+	require.Equal(t, Internal, CodeOf(nil))
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	cause := errors.New("pq: duplicate key value violates unique constraint")
+	err := Wrap(cause, Conflict, "record already exists")
+
+	require.ErrorIs(t, err, cause)
+	require.Equal(t, "record already exists: "+cause.Error(), err.Error())
+}
+
+func TestDetail(t *testing.T) {
+	cause := errors.New("pq: duplicate key value violates unique constraint")
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Plain", New(Forbidden, "not yours"), "not yours"},
+		{"WrappedContextKept", fmt.Errorf("%w: transfer 3 is settled", New(Unprocessable, "not reversible")),
+			"not reversible: transfer 3 is settled"},
+		{"CauseHidden", Wrap(cause, Conflict, "record already exists"), "record already exists"},
+		{"CauseHiddenUnderContext", fmt.Errorf("account 7: %w", Wrap(cause, Conflict, "record already exists")),
+			"account 7: record already exists"},
+		{"Unknown", errors.New("dial tcp: connection refused"), "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Detail(tt.err))
+		})
+	}
+}
+
+func TestExtensionsOf(t *testing.T) {
+	require.Nil(t, ExtensionsOf(New(NotFound, "gone")))
+
+	inner := &detailedError{
+		err:     New(InsufficientFunds, "insufficient funds"),
+		details: map[string]any{"available_balance": 5, "index": 0},
+	}
+	outer := &detailedError{
+		err:     fmt.Errorf("item: %w", inner),
+		details: map[string]any{"index": 2},
+	}
+
+	require.Equal(t, map[string]any{"available_balance": 5, "index": 2}, ExtensionsOf(outer))
+	require.Equal(t, InsufficientFunds, CodeOf(outer))
+}
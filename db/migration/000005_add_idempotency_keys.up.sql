@@ -0,0 +1,17 @@
+CREATE TABLE "idempotency_keys" (
+  "username" varchar NOT NULL,
+  "key" varchar NOT NULL,
+  "request_hash" varchar NOT NULL,
+  "response" jsonb NOT NULL DEFAULT ('{}'),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expires_at" timestamptz NOT NULL,
+  PRIMARY KEY ("username", "key")
+);
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "idempotency_keys" ("expires_at");
+
+COMMENT ON COLUMN "idempotency_keys"."username" IS 'user the key belongs to; keys are only unique per user';
+
+COMMENT ON COLUMN "idempotency_keys"."request_hash" IS 'fingerprint of the request the key was first used with';
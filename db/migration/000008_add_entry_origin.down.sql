@@ -0,0 +1,5 @@
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "entry_type";
+
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "transfer_id";
+
+DROP TYPE IF EXISTS "entry_type";
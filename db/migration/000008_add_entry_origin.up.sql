@@ -0,0 +1,67 @@
+CREATE TYPE "entry_type" AS ENUM (
+  'transfer_debit',
+  'transfer_credit',
+  'deposit',
+  'withdrawal',
+  'fee',
+  'adjustment'
+);
+
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "entry_type" entry_type;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+-- A transfer and its entries were written in one transaction, so existing
+-- transfer legs share the transfer's created_at. Every entry that could be
+-- a leg of a transfer is a candidate for it.
+CREATE TEMPORARY TABLE "entry_transfer_candidates" AS
+SELECT e."id" AS "entry_id",
+       t."id" AS "transfer_id",
+       (CASE WHEN e."amount" < 0 THEN 'transfer_debit' ELSE 'transfer_credit' END)::entry_type AS "entry_type"
+FROM "transfers" t
+JOIN "entries" e ON e."created_at" = t."created_at"
+WHERE (e."account_id" = t."from_account_id" AND e."amount" = -t."amount")
+   OR (e."account_id" = t."to_account_id" AND e."amount" = t."to_amount");
+
+-- A transfer is linked only when exactly one debit on its source account
+-- and one credit on its destination account match it, and neither could
+-- belong to another transfer.
+WITH "entry_matches" AS (
+  SELECT "entry_id", count(*) AS "transfers"
+  FROM "entry_transfer_candidates"
+  GROUP BY "entry_id"
+), "linkable" AS (
+  SELECT c."transfer_id"
+  FROM "entry_transfer_candidates" c
+  JOIN "entry_matches" m ON m."entry_id" = c."entry_id"
+  GROUP BY c."transfer_id"
+  HAVING count(*) FILTER (WHERE c."entry_type" = 'transfer_debit') = 1
+     AND count(*) FILTER (WHERE c."entry_type" = 'transfer_credit') = 1
+     AND max(m."transfers") = 1
+)
+UPDATE "entries" e
+SET "transfer_id" = c."transfer_id", "entry_type" = c."entry_type"
+FROM "entry_transfer_candidates" c
+JOIN "linkable" l ON l."transfer_id" = c."transfer_id"
+WHERE e."id" = c."entry_id";
+
+-- Ambiguous legs keep a NULL transfer_id, so reconciliation reports them
+-- for someone to attribute by hand.
+UPDATE "entries" e
+SET "entry_type" = c."entry_type"
+FROM "entry_transfer_candidates" c
+WHERE e."id" = c."entry_id"
+  AND e."entry_type" IS NULL;
+
+DROP TABLE "entry_transfer_candidates";
+
+-- anything else predates typed entries and can't be attributed
+UPDATE "entries" SET "entry_type" = 'adjustment' WHERE "entry_type" IS NULL;
+
+ALTER TABLE "entries" ALTER COLUMN "entry_type" SET NOT NULL;
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'set for transfer_debit and transfer_credit entries';
@@ -0,0 +1,15 @@
+CREATE TYPE "transfer_status" AS ENUM (
+  'completed',
+  'partially_reversed',
+  'reversed'
+);
+
+ALTER TABLE "transfers" ADD COLUMN "status" transfer_status NOT NULL DEFAULT 'completed';
+
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfers" ("reversal_of");
+
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'the transfer this one refunds, if any';
@@ -0,0 +1,26 @@
+DROP TABLE IF EXISTS "holds";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "available_balance";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "held_balance";
+
+-- enum values cannot be dropped, so the type is rebuilt without them;
+-- transfers that never settled have no entries and are removed
+DELETE FROM "transfers" WHERE "status" IN ('pending', 'authorized', 'failed');
+
+ALTER TABLE "transfers" ALTER COLUMN "status" DROP DEFAULT;
+
+ALTER TYPE "transfer_status" RENAME TO "transfer_status_old";
+
+CREATE TYPE "transfer_status" AS ENUM (
+  'completed',
+  'partially_reversed',
+  'reversed'
+);
+
+ALTER TABLE "transfers" ALTER COLUMN "status" TYPE "transfer_status"
+  USING (CASE "status"::text WHEN 'settled' THEN 'completed' ELSE "status"::text END)::"transfer_status";
+
+ALTER TABLE "transfers" ALTER COLUMN "status" SET DEFAULT 'completed';
+
+DROP TYPE "transfer_status_old";
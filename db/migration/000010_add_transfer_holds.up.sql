@@ -0,0 +1,35 @@
+ALTER TYPE "transfer_status" RENAME VALUE 'completed' TO 'settled';
+
+ALTER TYPE "transfer_status" ADD VALUE 'pending' BEFORE 'settled';
+
+ALTER TYPE "transfer_status" ADD VALUE 'authorized' BEFORE 'settled';
+
+ALTER TYPE "transfer_status" ADD VALUE 'failed' AFTER 'settled';
+
+ALTER TABLE "accounts" ADD COLUMN "held_balance" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_balance_check" CHECK ("held_balance" >= 0);
+
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint NOT NULL GENERATED ALWAYS AS ("balance" - "held_balance") STORED;
+
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "transfer_id" bigint UNIQUE NOT NULL,
+  "amount" bigint NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "released_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "holds" ADD CONSTRAINT "holds_amount_check" CHECK ("amount" > 0);
+
+CREATE INDEX ON "holds" ("expires_at") WHERE "released_at" IS NULL;
+
+COMMENT ON COLUMN "accounts"."held_balance" IS 'sum of the unreleased holds on the account';
+
+COMMENT ON COLUMN "holds"."released_at" IS 'when the hold was captured, voided or expired';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "scheduled_transfer_runs";
+
+DROP TABLE IF EXISTS "scheduled_transfers";
+
+DROP TYPE IF EXISTS "scheduled_run_status";
+
+DROP TYPE IF EXISTS "schedule_unit";
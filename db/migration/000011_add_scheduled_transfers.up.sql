@@ -0,0 +1,60 @@
+CREATE TYPE "schedule_unit" AS ENUM (
+  'day',
+  'week',
+  'month'
+);
+
+CREATE TYPE "scheduled_run_status" AS ENUM (
+  'succeeded',
+  'failed'
+);
+
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "schedule_unit" schedule_unit NOT NULL,
+  "schedule_every" integer NOT NULL DEFAULT 1,
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "next_run_at" timestamptz NOT NULL,
+  "active" boolean NOT NULL DEFAULT true,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "scheduled_for" timestamptz NOT NULL,
+  "status" scheduled_run_status NOT NULL,
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_amount_check" CHECK ("amount" > 0);
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_schedule_every_check" CHECK ("schedule_every" >= 1);
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfers_accounts_check" CHECK ("from_account_id" <> "to_account_id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "scheduled_transfers" ("from_account_id");
+
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "active";
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfers"."schedule_every" IS 'number of schedule units between runs';
+
+COMMENT ON COLUMN "scheduled_transfers"."end_at" IS 'no run is made after this time';
+
+COMMENT ON COLUMN "scheduled_transfer_runs"."scheduled_for" IS 'the next_run_at the run was made for';
@@ -0,0 +1,132 @@
+// Package migration embeds the database migrations and applies them with
+// golang-migrate, recording progress in the schema_migrations table.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// FS holds the up and down migrations of the schema
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Migrator applies the embedded migrations to one database
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New creates a migrator for the database at dbSource. Progress is logged
+// to logger; a nil logger discards it. The migrator holds its own
+// connection, released by Close.
+func New(dbSource string, logger *slog.Logger) (*Migrator, error) {
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, dbSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database for migration: %w", err)
+	}
+	m.Log = migrateLogger{logger}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up applies all pending migrations. Being up to date already is not an
+// error.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Steps applies n pending migrations, or rolls back -n when n is negative
+func (m *Migrator) Steps(n int) error {
+	return ignoreNoChange(m.m.Steps(n))
+}
+
+// Down rolls back every migration, leaving an empty schema
+func (m *Migrator) Down() error {
+	return ignoreNoChange(m.m.Down())
+}
+
+// Version returns the migration the database is at, or zero if none was
+// applied. Dirty is set when the last migration failed half way.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Force records version as applied and clean without running anything. It
+// is used to recover from a dirty migration after fixing it by hand.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// LatestVersion returns the version of the newest embedded migration, the
+// one the code expects the database to be at.
+func LatestVersion() (uint, error) {
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot read embedded migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// Close releases the migrator's connection
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// migrateLogger routes golang-migrate's progress messages to slog
+type migrateLogger struct {
+	logger *slog.Logger
+}
+
+func (l migrateLogger) Printf(format string, v ...interface{}) {
+	l.logger.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
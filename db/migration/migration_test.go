@@ -0,0 +1,163 @@
+package migration
+
+import (
+	"database/sql"
+	"io/fs"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/NoahFola/simple_bank/util"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_\w+\.(up|down)\.sql$`)
+
+func TestEmbeddedMigrationsArePaired(t *testing.T) {
+	entries, err := fs.ReadDir(FS, ".")
+	require.NoError(t, err)
+
+	directions := make(map[string][]string)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		require.NotNil(t, match, "unexpected file %s", entry.Name())
+		directions[match[1]] = append(directions[match[1]], match[2])
+	}
+
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+	require.Len(t, directions, int(latest), "migration versions must have no gaps")
+	for version, dirs := range directions {
+		require.ElementsMatch(t, []string{"up", "down"}, dirs, "migration %s", version)
+	}
+}
+
+// scratchDatabase creates an empty database on the configured server,
+// dropped again when the test ends, so the shared test database is left
+// alone. It returns the driver and the new database's source.
+func scratchDatabase(t *testing.T) (string, string) {
+	config, err := util.LoadConfig("../../.")
+	require.NoError(t, err)
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	dbName := "simple_bank_migration_" + util.RandomString(8)
+	_, err = conn.Exec("CREATE DATABASE " + dbName)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Exec("DROP DATABASE IF EXISTS " + dbName) })
+
+	source, err := url.Parse(config.DBSource)
+	require.NoError(t, err)
+	source.Path = "/" + dbName
+	return config.DBDriver, source.String()
+}
+
+// TestUpDown applies every migration and rolls them all back on a scratch
+// database.
+func TestUpDown(t *testing.T) {
+	driver, source := scratchDatabase(t)
+
+	migrator, err := New(source, nil)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.Up())
+	version, dirty, err := migrator.Version()
+	require.NoError(t, err)
+	require.Equal(t, latest, version)
+	require.False(t, dirty)
+
+	// Applying again is a no-op
+	require.NoError(t, migrator.Up())
+
+	require.NoError(t, migrator.Down())
+	version, dirty, err = migrator.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+	require.False(t, dirty)
+
+	// Every table but golang-migrate's own must be gone
+	scratch, err := sql.Open(driver, source)
+	require.NoError(t, err)
+	defer scratch.Close()
+
+	var tables int
+	err = scratch.QueryRow(`SELECT count(*) FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_name <> 'schema_migrations'`).Scan(&tables)
+	require.NoError(t, err)
+	require.Zero(t, tables, "tables left after rolling back every migration")
+}
+
+// TestEntryOriginBackfill checks that existing entries are only linked to a
+// transfer when nothing else could have been its leg.
+func TestEntryOriginBackfill(t *testing.T) {
+	driver, source := scratchDatabase(t)
+
+	migrator, err := New(source, nil)
+	require.NoError(t, err)
+	defer migrator.Close()
+	require.NoError(t, migrator.Steps(7))
+
+	conn, err := sql.Open(driver, source)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec(`
+		INSERT INTO users (username, hashed_password, full_name, email)
+		VALUES ('alice', 'x', 'Alice', 'alice@example.com');
+		INSERT INTO accounts (id, owner, balance, currency)
+		VALUES (1, 'alice', 0, 'USD'), (2, 'alice', 0, 'USD'), (3, 'alice', 0, 'USD');
+
+		-- transfer 1 has exactly one leg on each side
+		INSERT INTO transfers (id, from_account_id, to_account_id, amount, to_amount, created_at)
+		VALUES (1, 1, 2, 10, 10, '2024-01-01 10:00:00Z');
+		INSERT INTO entries (id, account_id, amount, created_at)
+		VALUES (1, 1, -10, '2024-01-01 10:00:00Z'), (2, 2, 10, '2024-01-01 10:00:00Z');
+
+		-- transfers 2 and 3 look alike, so their legs can't be told apart
+		INSERT INTO transfers (id, from_account_id, to_account_id, amount, to_amount, created_at)
+		VALUES (2, 1, 3, 5, 5, '2024-01-02 10:00:00Z'), (3, 1, 3, 5, 5, '2024-01-02 10:00:00Z');
+		INSERT INTO entries (id, account_id, amount, created_at)
+		VALUES (3, 1, -5, '2024-01-02 10:00:00Z'), (4, 3, 5, '2024-01-02 10:00:00Z'),
+		       (5, 1, -5, '2024-01-02 10:00:00Z'), (6, 3, 5, '2024-01-02 10:00:00Z');
+
+		-- an entry of the right amount and time on an unrelated account
+		INSERT INTO entries (id, account_id, amount, created_at)
+		VALUES (7, 3, 10, '2024-01-01 10:00:00Z');
+	`)
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.Steps(1))
+
+	tests := []struct {
+		entryID int64
+		// transferID is 0 when the entry must be left unlinked
+		transferID int64
+		entryType  string
+	}{
+		{1, 1, "transfer_debit"},
+		{2, 1, "transfer_credit"},
+		{3, 0, "transfer_debit"},
+		{4, 0, "transfer_credit"},
+		{5, 0, "transfer_debit"},
+		{6, 0, "transfer_credit"},
+		{7, 0, "adjustment"},
+	}
+	for _, tt := range tests {
+		var transferID sql.NullInt64
+		var entryType string
+		err := conn.QueryRow(`SELECT transfer_id, entry_type FROM entries WHERE id = $1`, tt.entryID).
+			Scan(&transferID, &entryType)
+		require.NoError(t, err)
+
+		require.Equal(t, tt.transferID != 0, transferID.Valid, "entry %d", tt.entryID)
+		require.Equal(t, tt.transferID, transferID.Int64, "entry %d", tt.entryID)
+		require.Equal(t, tt.entryType, entryType, "entry %d", tt.entryID)
+	}
+}
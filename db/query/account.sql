@@ -18,6 +18,21 @@ LIMIT $1
 OFFSET $2;
 
 
+-- name: ListAccountsByOwner :many
+SELECT * FROM accounts
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+
+-- name: ListAccountsByOwnerAfter :many
+SELECT * FROM accounts
+WHERE owner = sqlc.arg(owner) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+
 -- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
@@ -32,4 +47,34 @@ WHERE id = $1;
 -- name: GetAccountForUpdate :one
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
-FOR NO KEY UPDATE;
\ No newline at end of file
+FOR NO KEY UPDATE;
+
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $2
+WHERE id = $1
+RETURNING *;
+
+
+-- name: AddAccountBalance :one
+UPDATE accounts
+SET balance = balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+
+-- name: ListAccountLedgerTotalsAfter :many
+SELECT a.id, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id > sqlc.arg(after_id)
+GROUP BY a.id
+ORDER BY a.id
+LIMIT sqlc.arg('limit');
+
+
+-- name: AddAccountHeldBalance :one
+UPDATE accounts
+SET held_balance = held_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -12,11 +12,46 @@ OFFSET $2;
 
 -- name: CreateEntry :one
 INSERT INTO entries (
-  account_id, amount
+  account_id, amount, transfer_id, entry_type
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4
 ) RETURNING *;
 
+
+-- name: ListEntriesByAccount :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+ORDER BY id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+
+-- name: ListEntriesByAccountAfter :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id) AND id > sqlc.arg(after_id)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+
+-- name: ListOrphanEntriesAfter :many
+-- Transfer legs must link to their transfer and no other entry may.
+SELECT * FROM entries
+WHERE id > sqlc.arg(after_id)
+  AND (entry_type IN ('transfer_debit', 'transfer_credit')) <> (transfer_id IS NOT NULL)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+
+-- name: GetAccountEntriesTotal :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM entries
+WHERE account_id = $1;
+
+
 -- -- name: UpdateEntry :one
 -- UPDATE entries
 -- SET amount = $2
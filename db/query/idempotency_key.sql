@@ -0,0 +1,25 @@
+-- name: ClaimIdempotencyKey :execrows
+-- Inserts the key, or takes over an expired one. Affects no rows when a
+-- live key already exists, in which case the caller should replay it.
+INSERT INTO idempotency_keys (
+  username, key, request_hash, expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (username, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    response = '{}',
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= now();
+
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 LIMIT 1;
+
+
+-- name: UpdateIdempotencyKeyResponse :exec
+UPDATE idempotency_keys
+SET response = $3
+WHERE username = $1 AND key = $2;
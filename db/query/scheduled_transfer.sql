@@ -0,0 +1,83 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  from_account_id, to_account_id, amount, schedule_unit, schedule_every,
+  start_at, end_at, next_run_at
+) VALUES (
+  sqlc.arg(from_account_id), sqlc.arg(to_account_id), sqlc.arg(amount),
+  sqlc.arg(schedule_unit), sqlc.arg(schedule_every),
+  sqlc.arg(start_at), sqlc.narg(end_at), sqlc.arg(start_at)
+) RETURNING *;
+
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+
+-- name: ListScheduledTransfersByAccount :many
+SELECT * FROM scheduled_transfers
+WHERE from_account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+
+-- name: ListScheduledTransfersByAccountAfter :many
+SELECT * FROM scheduled_transfers
+WHERE from_account_id = sqlc.arg(from_account_id) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+
+-- name: UpdateScheduledTransfer :one
+-- Leaves every column whose argument is null as it is; clear_end_at removes
+-- the end of the order.
+UPDATE scheduled_transfers
+SET amount = COALESCE(sqlc.narg(amount), amount),
+  end_at = CASE WHEN sqlc.arg(clear_end_at)::boolean THEN NULL
+    ELSE COALESCE(sqlc.narg(end_at), end_at) END,
+  next_run_at = COALESCE(sqlc.narg(next_run_at), next_run_at),
+  active = COALESCE(sqlc.narg(active), active)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+
+-- name: DeleteScheduledTransfer :exec
+DELETE FROM scheduled_transfers
+WHERE id = $1;
+
+
+-- name: ClaimDueScheduledTransfer :one
+-- Locks the most overdue scheduled transfer, skipping any another worker
+-- has already claimed.
+SELECT * FROM scheduled_transfers
+WHERE active
+  AND next_run_at <= sqlc.arg(now)
+  AND (end_at IS NULL OR next_run_at <= end_at)
+ORDER BY next_run_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+
+-- name: AdvanceScheduledTransfer :one
+UPDATE scheduled_transfers
+SET next_run_at = $2,
+  active = $3
+WHERE id = $1
+RETURNING *;
+
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id, scheduled_for, status, transfer_id, error
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
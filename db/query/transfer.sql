@@ -1,8 +1,8 @@
 -- name: CreateTransfer :one
 INSERT INTO transfers (
-  from_account_id, to_account_id, amount
+  from_account_id, to_account_id, amount, to_amount, exchange_rate, reversal_of, status
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 
@@ -11,6 +11,26 @@ SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+
+-- name: GetTransferReversedTotals :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS debited,
+  COALESCE(SUM(to_amount), 0)::bigint AS refunded
+FROM transfers
+WHERE reversal_of = sqlc.arg(transfer_id)::bigint;
+
+
+-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET status = $2
+WHERE id = $1
+RETURNING *;
+
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 ORDER BY id
@@ -18,6 +38,42 @@ LIMIT $1
 OFFSET $2;
 
 
+-- name: ListTransfersByAccount :many
+-- Pass the account ID as from_account_id for outgoing transfers, as
+-- to_account_id for incoming ones, or as both for either direction.
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.narg(from_account_id) OR to_account_id = sqlc.narg(to_account_id))
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+ORDER BY id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+
+-- name: ListTransfersByAccountAfter :many
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.narg(from_account_id) OR to_account_id = sqlc.narg(to_account_id))
+  AND id > sqlc.arg(after_id)
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+
+-- name: ListTransferEntryCountsAfter :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.status,
+  (SELECT count(*) FROM entries e
+    WHERE e.transfer_id = t.id AND e.entry_type = 'transfer_debit'
+      AND e.account_id = t.from_account_id AND e.amount = -t.amount) AS debit_entries,
+  (SELECT count(*) FROM entries e
+    WHERE e.transfer_id = t.id AND e.entry_type = 'transfer_credit'
+      AND e.account_id = t.to_account_id AND e.amount = t.to_amount) AS credit_entries
+FROM transfers t
+WHERE t.id > sqlc.arg(after_id)
+ORDER BY t.id
+LIMIT sqlc.arg('limit');
+
+
 -- -- name: UpdateTransfer :one
 -- UPDATE transfers
 -- SET amount = $2
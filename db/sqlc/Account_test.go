@@ -9,10 +9,16 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithCurrency(t, util.RandomCurrency())
+}
+
+func createRandomAccountWithCurrency(t *testing.T, currency string) Account {
+	user := createRandomUser(t)
+
 	arg := CreateAccountParams{
-		Owner:    util.RandomOwner(),
+		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -33,6 +39,30 @@ func TestCreateAccount(t *testing.T) {
 	createRandomAccount(t)
 }
 
+func TestCreateAccountUnknownOwner(t *testing.T) {
+	arg := CreateAccountParams{
+		Owner:    util.RandomOwner(),
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+	}
+
+	_, err := testQueries.CreateAccount(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestCreateAccountDuplicateCurrency(t *testing.T) {
+	account1 := createRandomAccount(t)
+
+	arg := CreateAccountParams{
+		Owner:    account1.Owner,
+		Balance:  util.RandomMoney(),
+		Currency: account1.Currency,
+	}
+
+	_, err := testQueries.CreateAccount(context.Background(), arg)
+	require.Error(t, err)
+}
+
 func TestGetAccount(t *testing.T) {
 	account1 := createRandomAccount(t)
 	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
@@ -96,3 +126,56 @@ func TestListAccounts(t *testing.T) {
 		require.NotEmpty(t, account)
 	}
 }
+
+func TestListAccountsByOwner(t *testing.T) {
+	var lastAccount Account
+	for i := 0; i < 10; i++ {
+		lastAccount = createRandomAccount(t)
+	}
+
+	arg := ListAccountsByOwnerParams{
+		Owner:  lastAccount.Owner,
+		Limit:  5,
+		Offset: 0,
+	}
+
+	accounts, err := testQueries.ListAccountsByOwner(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, accounts)
+
+	for _, account := range accounts {
+		require.NotEmpty(t, account)
+		require.Equal(t, lastAccount.Owner, account.Owner)
+	}
+}
+
+func TestListAccountsByOwnerAfter(t *testing.T) {
+	user := createRandomUser(t)
+
+	var accounts []Account
+	for _, currency := range []string{util.USD, util.EUR, util.CAD} {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  util.RandomMoney(),
+			Currency: currency,
+		})
+		require.NoError(t, err)
+		accounts = append(accounts, account)
+	}
+
+	page1, err := testQueries.ListAccountsByOwnerAfter(context.Background(), ListAccountsByOwnerAfterParams{
+		Owner:   user.Username,
+		AfterID: 0,
+		Limit:   2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, accounts[:2], page1)
+
+	page2, err := testQueries.ListAccountsByOwnerAfter(context.Background(), ListAccountsByOwnerAfterParams{
+		Owner:   user.Username,
+		AfterID: page1[1].ID,
+		Limit:   2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, accounts[2:], page2)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/NoahFola/simple_bank/util"
+)
+
+const (
+	connectBaseBackoff = 250 * time.Millisecond
+	connectMaxBackoff  = 5 * time.Second
+)
+
+// Open opens the configured database, sizes its connection pool and waits
+// until it answers a ping. Failed pings are retried with a growing wait for
+// up to config.DBConnectTimeout, or until ctx is done when it is zero, so the
+// service can start before Postgres has finished starting.
+func Open(ctx context.Context, config util.Config, logger *slog.Logger) (*sql.DB, error) {
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		return nil, err
+	}
+	configurePool(conn, config)
+
+	if err := waitForDB(ctx, conn, config.DBConnectTimeout, logger); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// configurePool applies the pool settings that are set, leaving
+// database/sql's defaults in place for the rest; a zero would otherwise
+// mean, for instance, keeping no idle connections at all.
+func configurePool(conn *sql.DB, config util.Config) {
+	if config.DBMaxOpenConns > 0 {
+		conn.SetMaxOpenConns(config.DBMaxOpenConns)
+	}
+	if config.DBMaxIdleConns > 0 {
+		conn.SetMaxIdleConns(config.DBMaxIdleConns)
+	}
+	if config.DBConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(config.DBConnMaxLifetime)
+	}
+	if config.DBConnMaxIdleTime > 0 {
+		conn.SetConnMaxIdleTime(config.DBConnMaxIdleTime)
+	}
+}
+
+// waitForDB pings conn until it answers, ctx is done or timeout elapses
+func waitForDB(ctx context.Context, conn *sql.DB, timeout time.Duration, logger *slog.Logger) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := conn.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait := connectBackoff(attempt)
+		logger.Warn("database is not reachable yet", "attempt", attempt, "retry_in", wait, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// connectBackoff returns how long to wait after the given failed attempt,
+// counting from 1. The wait doubles with every attempt up to a ceiling.
+func connectBackoff(attempt int) time.Duration {
+	if attempt < 32 && connectBaseBackoff<<(attempt-1) < connectMaxBackoff {
+		return connectBaseBackoff << (attempt - 1)
+	}
+	return connectMaxBackoff
+}
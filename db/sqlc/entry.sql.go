@@ -7,35 +7,58 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
-  account_id, amount
+  account_id, amount, transfer_id, entry_type
 ) VALUES (
-  $1, $2
-) RETURNING id, account_id, amount, created_at
+  $1, $2, $3, $4
+) RETURNING id, account_id, amount, created_at, transfer_id, entry_type
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID  int64     `json:"account_id"`
+	Amount     int64     `json:"amount"`
+	TransferID *int64    `json:"transfer_id"`
+	EntryType  EntryType `json:"entry_type"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.EntryType,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.EntryType,
 	)
 	return i, err
 }
 
+const getAccountEntriesTotal = `-- name: GetAccountEntriesTotal :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM entries
+WHERE account_id = $1
+`
+
+func (q *Queries) GetAccountEntriesTotal(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAccountEntriesTotal, accountID)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, entry_type FROM entries
 WHERE id = $1 
 LIMIT 1
 `
@@ -48,12 +71,14 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.EntryType,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, entry_type FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -78,6 +103,159 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesByAccount = `-- name: ListEntriesByAccount :many
+SELECT id, account_id, amount, created_at, transfer_id, entry_type FROM entries
+WHERE account_id = $1
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+ORDER BY id
+LIMIT $5
+OFFSET $4
+`
+
+type ListEntriesByAccountParams struct {
+	AccountID int64        `json:"account_id"`
+	StartTime sql.NullTime `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+	Offset    int32        `json:"offset"`
+	Limit     int32        `json:"limit"`
+}
+
+func (q *Queries) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesByAccount,
+		arg.AccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesByAccountAfter = `-- name: ListEntriesByAccountAfter :many
+SELECT id, account_id, amount, created_at, transfer_id, entry_type FROM entries
+WHERE account_id = $1 AND id > $2
+  AND ($3::timestamptz IS NULL OR created_at >= $3)
+  AND ($4::timestamptz IS NULL OR created_at < $4)
+ORDER BY id
+LIMIT $5
+`
+
+type ListEntriesByAccountAfterParams struct {
+	AccountID int64        `json:"account_id"`
+	AfterID   int64        `json:"after_id"`
+	StartTime sql.NullTime `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+	Limit     int32        `json:"limit"`
+}
+
+func (q *Queries) ListEntriesByAccountAfter(ctx context.Context, arg ListEntriesByAccountAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesByAccountAfter,
+		arg.AccountID,
+		arg.AfterID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanEntriesAfter = `-- name: ListOrphanEntriesAfter :many
+SELECT id, account_id, amount, created_at, transfer_id, entry_type FROM entries
+WHERE id > $1
+  AND (entry_type IN ('transfer_debit', 'transfer_credit')) <> (transfer_id IS NOT NULL)
+ORDER BY id
+LIMIT $2
+`
+
+type ListOrphanEntriesAfterParams struct {
+	AfterID int64 `json:"after_id"`
+	Limit   int32 `json:"limit"`
+}
+
+// Transfer legs must link to their transfer and no other entry may.
+func (q *Queries) ListOrphanEntriesAfter(ctx context.Context, arg ListOrphanEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listOrphanEntriesAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
 		); err != nil {
 			return nil, err
 		}
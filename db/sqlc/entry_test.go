@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ func createRandomEntry(t *testing.T, account Account) Entry {
 	arg := CreateEntryParams{
 		AccountID: account.ID,
 		Amount:    util.RandomMoney(),
+		EntryType: EntryTypeDeposit,
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -21,6 +23,8 @@ func createRandomEntry(t *testing.T, account Account) Entry {
 
 	require.Equal(t, arg.AccountID, entry.AccountID)
 	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, arg.EntryType, entry.EntryType)
+	require.Nil(t, entry.TransferID)
 
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
@@ -65,3 +69,53 @@ func TestListEntries(t *testing.T) {
 		require.NotEmpty(t, entry)
 	}
 }
+
+func TestListEntriesByAccount(t *testing.T) {
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		createRandomEntry(t, account)
+		createRandomEntry(t, other)
+	}
+
+	arg := ListEntriesByAccountParams{
+		AccountID: account.ID,
+		Limit:     10,
+		Offset:    0,
+	}
+
+	entries, err := testQueries.ListEntriesByAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+
+	for _, entry := range entries {
+		require.Equal(t, account.ID, entry.AccountID)
+	}
+
+	// a range that ends before the entries were created matches nothing
+	arg.EndTime = sql.NullTime{Time: entries[0].CreatedAt, Valid: true}
+	entries, err = testQueries.ListEntriesByAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestListEntriesByAccountAfter(t *testing.T) {
+	account := createRandomAccount(t)
+	var created []Entry
+	for i := 0; i < 5; i++ {
+		created = append(created, createRandomEntry(t, account))
+	}
+
+	arg := ListEntriesByAccountAfterParams{
+		AccountID: account.ID,
+		AfterID:   created[1].ID,
+		Limit:     10,
+	}
+
+	entries, err := testQueries.ListEntriesByAccountAfter(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		require.Equal(t, created[i+2].ID, entry.ID)
+	}
+}
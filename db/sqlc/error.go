@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/lib/pq"
+)
+
+// Postgres error codes the store translates
+const (
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	CheckViolation       = "23514"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// ErrRecordNotFound is returned when a query finds no row. It unwraps to
+// sql.ErrNoRows.
+var ErrRecordNotFound = apperr.Wrap(sql.ErrNoRows, apperr.NotFound, "record not found")
+
+// ErrorCode returns the Postgres error code behind err, or an empty string
+// if err didn't come from Postgres.
+func ErrorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+// TranslateError turns the errors of database/sql and Postgres that callers
+// can act on into apperr errors, keeping the original as the cause so that
+// errors.Is(err, sql.ErrNoRows) and ErrorCode still work. Errors that
+// already carry a code, and unexpected ones, are returned unchanged.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := apperr.As(err); ok {
+		return err
+	}
+
+	if err == sql.ErrNoRows {
+		return ErrRecordNotFound
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.Wrap(err, apperr.NotFound, "record not found")
+	}
+
+	switch ErrorCode(err) {
+	case UniqueViolation:
+		return apperr.Wrap(err, apperr.Conflict, "record already exists")
+	case ForeignKeyViolation:
+		return apperr.Wrap(err, apperr.Conflict, "record references a missing record or is still referenced")
+	case CheckViolation:
+		return apperr.Wrap(err, apperr.ValidationFailed, "value is out of range")
+	case SerializationFailure, DeadlockDetected:
+		return apperr.Wrap(err, apperr.Conflict, "request conflicted with another one, try again")
+	}
+
+	return err
+}
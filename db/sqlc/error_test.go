@@ -0,0 +1,64 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code apperr.Code
+	}{
+		{"NoRows", sql.ErrNoRows, apperr.NotFound},
+		{"WrappedNoRows", fmt.Errorf("account 3: %w", sql.ErrNoRows), apperr.NotFound},
+		{"UniqueViolation", &pq.Error{Code: UniqueViolation}, apperr.Conflict},
+		{"ForeignKeyViolation", &pq.Error{Code: ForeignKeyViolation}, apperr.Conflict},
+		{"CheckViolation", &pq.Error{Code: CheckViolation}, apperr.ValidationFailed},
+		{"SerializationFailure", &pq.Error{Code: SerializationFailure}, apperr.Conflict},
+		{"AlreadyTranslated", ErrHoldExpired, apperr.Unprocessable},
+		{"Unexpected", &pq.Error{Code: "57014"}, apperr.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TranslateError(tt.err)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.code, apperr.CodeOf(err))
+		})
+	}
+
+	require.NoError(t, TranslateError(nil))
+	require.Same(t, ErrRecordNotFound, TranslateError(sql.ErrNoRows))
+}
+
+func TestErrorCode(t *testing.T) {
+	err := TranslateError(&pq.Error{Code: UniqueViolation})
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+	require.Empty(t, ErrorCode(errors.New("boom")))
+}
+
+// Every query must be wrapped in store_errors.go; one that is only promoted
+// from Queries would hand raw database errors to callers.
+func TestStoreTranslatesEveryQuery(t *testing.T) {
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+	store := reflect.TypeOf(&SQLStore{})
+
+	for i := 0; i < querier.NumMethod(); i++ {
+		name := querier.Method(i).Name
+		method, ok := store.MethodByName(name)
+		require.True(t, ok, name)
+
+		file, _ := runtime.FuncForPC(method.Func.Pointer()).FileLine(method.Func.Pointer())
+		require.NotEqual(t, "<autogenerated>", file, "%s is not wrapped by SQLStore", name)
+	}
+}
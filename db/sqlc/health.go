@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+)
+
+// MigrationVersion is the state golang-migrate records in the database
+type MigrationVersion struct {
+	Version int64 `json:"version"`
+	// Dirty is set when a migration failed half way and must be fixed by hand
+	Dirty bool `json:"dirty"`
+}
+
+// Ping checks that the database can be reached
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the migration the database is at. It fails with
+// ErrRecordNotFound when no migration has been applied.
+func (store *SQLStore) MigrationVersion(ctx context.Context) (MigrationVersion, error) {
+	var version MigrationVersion
+	err := store.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").
+		Scan(&version.Version, &version.Dirty)
+	return version, TranslateError(err)
+}
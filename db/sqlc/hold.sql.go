@@ -0,0 +1,131 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: hold.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createHold = `-- name: CreateHold :one
+INSERT INTO holds (
+  account_id, transfer_id, amount, expires_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING id, account_id, transfer_id, amount, expires_at, released_at, created_at
+`
+
+type CreateHoldParams struct {
+	AccountID  int64     `json:"account_id"`
+	TransferID int64     `json:"transfer_id"`
+	Amount     int64     `json:"amount"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, createHold,
+		arg.AccountID,
+		arg.TransferID,
+		arg.Amount,
+		arg.ExpiresAt,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Amount,
+		&i.ExpiresAt,
+		&i.ReleasedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getHoldByTransfer = `-- name: GetHoldByTransfer :one
+SELECT id, account_id, transfer_id, amount, expires_at, released_at, created_at FROM holds
+WHERE transfer_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetHoldByTransfer(ctx context.Context, transferID int64) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, getHoldByTransfer, transferID)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Amount,
+		&i.ExpiresAt,
+		&i.ReleasedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listExpiredHolds = `-- name: ListExpiredHolds :many
+SELECT id, account_id, transfer_id, amount, expires_at, released_at, created_at FROM holds
+WHERE released_at IS NULL AND expires_at <= $1
+ORDER BY expires_at
+LIMIT $2
+`
+
+type ListExpiredHoldsParams struct {
+	ExpiredAt time.Time `json:"expired_at"`
+	Limit     int32     `json:"limit"`
+}
+
+func (q *Queries) ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error) {
+	rows, err := q.db.QueryContext(ctx, listExpiredHolds, arg.ExpiredAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Hold{}
+	for rows.Next() {
+		var i Hold
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.TransferID,
+			&i.Amount,
+			&i.ExpiresAt,
+			&i.ReleasedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseHold = `-- name: ReleaseHold :one
+UPDATE holds
+SET released_at = now()
+WHERE id = $1 AND released_at IS NULL
+RETURNING id, account_id, transfer_id, amount, expires_at, released_at, created_at
+`
+
+func (q *Queries) ReleaseHold(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, releaseHold, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Amount,
+		&i.ExpiresAt,
+		&i.ReleasedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
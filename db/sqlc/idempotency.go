@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is replayed
+// with a request that differs from the one it was first used with.
+var ErrIdempotencyKeyReused = apperr.New(apperr.Unprocessable, "idempotency key was already used with a different request")
+
+// reserveIdempotencyKey records username's key for a new request inside the
+// current transaction. If the key is still live, the response stored for it is
+// returned instead so the caller can replay it without doing the work again.
+// Concurrent requests with the same key block on the insert until the first
+// transaction commits or rolls back.
+func reserveIdempotencyKey(ctx context.Context, q *Queries, username, key, requestHash string, ttl time.Duration) (json.RawMessage, error) {
+	claimed, err := q.ClaimIdempotencyKey(ctx, ClaimIdempotencyKeyParams{
+		Username:    username,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claimed > 0 {
+		return nil, nil
+	}
+
+	existing, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username: username,
+		Key:      key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return existing.Response, nil
+}
+
+// saveIdempotencyResponse stores the response of a request so later replays
+// of its key can return it.
+func saveIdempotencyResponse(ctx context.Context, q *Queries, username, key string, response any) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	return q.UpdateIdempotencyKeyResponse(ctx, UpdateIdempotencyKeyResponseParams{
+		Username: username,
+		Key:      key,
+		Response: data,
+	})
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"log"
+	"log/slog"
 	"os"
 	"testing"
 
@@ -13,6 +14,10 @@ import (
 var testQueries *Queries
 var testDB *sql.DB
 
+// testLogger shows the steps of each transaction, tagged with the name the
+// test gave it.
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
 func TestMain(m *testing.M) {
 	config, err := util.LoadConfig("../../.")
 	if err != nil {
@@ -5,15 +5,203 @@
 package db
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+type EntryType string
+
+const (
+	EntryTypeTransferDebit  EntryType = "transfer_debit"
+	EntryTypeTransferCredit EntryType = "transfer_credit"
+	EntryTypeDeposit        EntryType = "deposit"
+	EntryTypeWithdrawal     EntryType = "withdrawal"
+	EntryTypeFee            EntryType = "fee"
+	EntryTypeAdjustment     EntryType = "adjustment"
+)
+
+func (e *EntryType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EntryType(s)
+	case string:
+		*e = EntryType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EntryType: %T", src)
+	}
+	return nil
+}
+
+type NullEntryType struct {
+	EntryType EntryType `json:"entry_type"`
+	Valid     bool      `json:"valid"` // Valid is true if EntryType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEntryType) Scan(value interface{}) error {
+	if value == nil {
+		ns.EntryType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EntryType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEntryType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EntryType), nil
+}
+
+type ScheduleUnit string
+
+const (
+	ScheduleUnitDay   ScheduleUnit = "day"
+	ScheduleUnitWeek  ScheduleUnit = "week"
+	ScheduleUnitMonth ScheduleUnit = "month"
 )
 
+func (e *ScheduleUnit) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduleUnit(s)
+	case string:
+		*e = ScheduleUnit(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduleUnit: %T", src)
+	}
+	return nil
+}
+
+type NullScheduleUnit struct {
+	ScheduleUnit ScheduleUnit `json:"schedule_unit"`
+	Valid        bool         `json:"valid"` // Valid is true if ScheduleUnit is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullScheduleUnit) Scan(value interface{}) error {
+	if value == nil {
+		ns.ScheduleUnit, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ScheduleUnit.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullScheduleUnit) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ScheduleUnit), nil
+}
+
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunStatusSucceeded ScheduledRunStatus = "succeeded"
+	ScheduledRunStatusFailed    ScheduledRunStatus = "failed"
+)
+
+func (e *ScheduledRunStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledRunStatus(s)
+	case string:
+		*e = ScheduledRunStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledRunStatus: %T", src)
+	}
+	return nil
+}
+
+type NullScheduledRunStatus struct {
+	ScheduledRunStatus ScheduledRunStatus `json:"scheduled_run_status"`
+	Valid              bool               `json:"valid"` // Valid is true if ScheduledRunStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullScheduledRunStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ScheduledRunStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ScheduledRunStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullScheduledRunStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ScheduledRunStatus), nil
+}
+
+type TransferStatus string
+
+const (
+	TransferStatusPending           TransferStatus = "pending"
+	TransferStatusAuthorized        TransferStatus = "authorized"
+	TransferStatusSettled           TransferStatus = "settled"
+	TransferStatusFailed            TransferStatus = "failed"
+	TransferStatusPartiallyReversed TransferStatus = "partially_reversed"
+	TransferStatusReversed          TransferStatus = "reversed"
+)
+
+func (e *TransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TransferStatus(s)
+	case string:
+		*e = TransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TransferStatus: %T", src)
+	}
+	return nil
+}
+
+type NullTransferStatus struct {
+	TransferStatus TransferStatus `json:"transfer_status"`
+	Valid          bool           `json:"valid"` // Valid is true if TransferStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTransferStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.TransferStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TransferStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTransferStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TransferStatus), nil
+}
+
 type Account struct {
 	ID        int64     `json:"id"`
 	Owner     string    `json:"owner"`
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero the balance may go
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// sum of the unreleased holds on the account
+	HeldBalance      int64 `json:"held_balance"`
+	AvailableBalance int64 `json:"available_balance"`
 }
 
 type Entry struct {
@@ -22,13 +210,94 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// set for transfer_debit and transfer_credit entries
+	TransferID *int64    `json:"transfer_id"`
+	EntryType  EntryType `json:"entry_type"`
+}
+
+type Hold struct {
+	ID         int64     `json:"id"`
+	AccountID  int64     `json:"account_id"`
+	TransferID int64     `json:"transfer_id"`
+	Amount     int64     `json:"amount"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// when the hold was captured, voided or expired
+	ReleasedAt sql.NullTime `json:"released_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	// user the key belongs to; keys are only unique per user
+	Username string `json:"username"`
+	Key      string `json:"key"`
+	// fingerprint of the request the key was first used with
+	RequestHash string          `json:"request_hash"`
+	Response    json.RawMessage `json:"response"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64        `json:"id"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        int64        `json:"amount"`
+	ScheduleUnit  ScheduleUnit `json:"schedule_unit"`
+	// number of schedule units between runs
+	ScheduleEvery int32     `json:"schedule_every"`
+	StartAt       time.Time `json:"start_at"`
+	// no run is made after this time
+	EndAt     *time.Time `json:"end_at"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64 `json:"id"`
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// the next_run_at the run was made for
+	ScheduledFor time.Time          `json:"scheduled_for"`
+	Status       ScheduledRunStatus `json:"status"`
+	TransferID   *int64             `json:"transfer_id"`
+	Error        string             `json:"error"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// must be positive
+	// must be positive, in the source account currency
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// must be positive, in the destination account currency
+	ToAmount int64 `json:"to_amount"`
+	// units of destination currency per unit of source currency
+	ExchangeRate string         `json:"exchange_rate"`
+	Status       TransferStatus `json:"status"`
+	// the transfer this one refunds, if any
+	ReversalOf *int64 `json:"reversal_of"`
+}
+
+type User struct {
+	Username          string    `json:"username"`
+	HashedPassword    string    `json:"hashed_password"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+	// depositor or admin
+	Role string `json:"role"`
 }
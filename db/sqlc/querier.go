@@ -6,21 +6,71 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error)
+	AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	// Locks the most overdue scheduled transfer, skipping any another worker
+	// has already claimed.
+	ClaimDueScheduledTransfer(ctx context.Context, now time.Time) (ScheduledTransfer, error)
+	// Inserts the key, or takes over an expired one. Affects no rows when a
+	// live key already exists, in which case the caller should replay it.
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteScheduledTransfer(ctx context.Context, id int64) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountEntriesTotal(ctx context.Context, accountID int64) (int64, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetHoldByTransfer(ctx context.Context, transferID int64) (Hold, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferReversedTotals(ctx context.Context, transferID int64) (GetTransferReversedTotalsRow, error)
+	GetUser(ctx context.Context, username string) (User, error)
+	ListAccountLedgerTotalsAfter(ctx context.Context, arg ListAccountLedgerTotalsAfterParams) ([]ListAccountLedgerTotalsAfterRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error)
+	ListAccountsByOwnerAfter(ctx context.Context, arg ListAccountsByOwnerAfterParams) ([]Account, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error)
+	ListEntriesByAccountAfter(ctx context.Context, arg ListEntriesByAccountAfterParams) ([]Entry, error)
+	ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error)
+	// Transfer legs must link to their transfer and no other entry may.
+	ListOrphanEntriesAfter(ctx context.Context, arg ListOrphanEntriesAfterParams) ([]Entry, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfersByAccount(ctx context.Context, arg ListScheduledTransfersByAccountParams) ([]ScheduledTransfer, error)
+	ListScheduledTransfersByAccountAfter(ctx context.Context, arg ListScheduledTransfersByAccountAfterParams) ([]ScheduledTransfer, error)
+	ListTransferEntryCountsAfter(ctx context.Context, arg ListTransferEntryCountsAfterParams) ([]ListTransferEntryCountsAfterRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	// Pass the account ID as from_account_id for outgoing transfers, as
+	// to_account_id for incoming ones, or as both for either direction.
+	ListTransfersByAccount(ctx context.Context, arg ListTransfersByAccountParams) ([]Transfer, error)
+	ListTransfersByAccountAfter(ctx context.Context, arg ListTransfersByAccountAfterParams) ([]Transfer, error)
+	ReleaseHold(ctx context.Context, id int64) (Hold, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) error
+	// Leaves every column whose argument is null as it is; clear_end_at removes
+	// the end of the order.
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,385 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const advanceScheduledTransfer = `-- name: AdvanceScheduledTransfer :one
+UPDATE scheduled_transfers
+SET next_run_at = $2,
+  active = $3
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at
+`
+
+type AdvanceScheduledTransferParams struct {
+	ID        int64     `json:"id"`
+	NextRunAt time.Time `json:"next_run_at"`
+	Active    bool      `json:"active"`
+}
+
+func (q *Queries) AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, advanceScheduledTransfer, arg.ID, arg.NextRunAt, arg.Active)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.ScheduleUnit,
+		&i.ScheduleEvery,
+		&i.StartAt,
+		&i.EndAt,
+		&i.NextRunAt,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const claimDueScheduledTransfer = `-- name: ClaimDueScheduledTransfer :one
+SELECT id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at FROM scheduled_transfers
+WHERE active
+  AND next_run_at <= $1
+  AND (end_at IS NULL OR next_run_at <= end_at)
+ORDER BY next_run_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+// Locks the most overdue scheduled transfer, skipping any another worker
+// has already claimed.
+func (q *Queries) ClaimDueScheduledTransfer(ctx context.Context, now time.Time) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, claimDueScheduledTransfer, now)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.ScheduleUnit,
+		&i.ScheduleEvery,
+		&i.StartAt,
+		&i.EndAt,
+		&i.NextRunAt,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  from_account_id, to_account_id, amount, schedule_unit, schedule_every,
+  start_at, end_at, next_run_at
+) VALUES (
+  $1, $2, $3,
+  $4, $5,
+  $6, $7, $6
+) RETURNING id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at
+`
+
+type CreateScheduledTransferParams struct {
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        int64        `json:"amount"`
+	ScheduleUnit  ScheduleUnit `json:"schedule_unit"`
+	ScheduleEvery int32        `json:"schedule_every"`
+	StartAt       time.Time    `json:"start_at"`
+	EndAt         *time.Time   `json:"end_at"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ScheduleUnit,
+		arg.ScheduleEvery,
+		arg.StartAt,
+		arg.EndAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.ScheduleUnit,
+		&i.ScheduleEvery,
+		&i.StartAt,
+		&i.EndAt,
+		&i.NextRunAt,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransferRun = `-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id, scheduled_for, status, transfer_id, error
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING id, scheduled_transfer_id, scheduled_for, status, transfer_id, error, created_at
+`
+
+type CreateScheduledTransferRunParams struct {
+	ScheduledTransferID int64              `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time          `json:"scheduled_for"`
+	Status              ScheduledRunStatus `json:"status"`
+	TransferID          *int64             `json:"transfer_id"`
+	Error               string             `json:"error"`
+}
+
+func (q *Queries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransferRun,
+		arg.ScheduledTransferID,
+		arg.ScheduledFor,
+		arg.Status,
+		arg.TransferID,
+		arg.Error,
+	)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.ScheduledFor,
+		&i.Status,
+		&i.TransferID,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteScheduledTransfer = `-- name: DeleteScheduledTransfer :exec
+DELETE FROM scheduled_transfers
+WHERE id = $1
+`
+
+func (q *Queries) DeleteScheduledTransfer(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteScheduledTransfer, id)
+	return err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+SELECT id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.ScheduleUnit,
+		&i.ScheduleEvery,
+		&i.StartAt,
+		&i.EndAt,
+		&i.NextRunAt,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listScheduledTransferRuns = `-- name: ListScheduledTransferRuns :many
+SELECT id, scheduled_transfer_id, scheduled_for, status, transfer_id, error, created_at FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransferRunsParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	Limit               int32 `json:"limit"`
+	Offset              int32 `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransferRuns, arg.ScheduledTransferID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransferRun{}
+	for rows.Next() {
+		var i ScheduledTransferRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.ScheduledTransferID,
+			&i.ScheduledFor,
+			&i.Status,
+			&i.TransferID,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfersByAccount = `-- name: ListScheduledTransfersByAccount :many
+SELECT id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at FROM scheduled_transfers
+WHERE from_account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransfersByAccountParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	Limit         int32 `json:"limit"`
+	Offset        int32 `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransfersByAccount(ctx context.Context, arg ListScheduledTransfersByAccountParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfersByAccount, arg.FromAccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.ScheduleUnit,
+			&i.ScheduleEvery,
+			&i.StartAt,
+			&i.EndAt,
+			&i.NextRunAt,
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfersByAccountAfter = `-- name: ListScheduledTransfersByAccountAfter :many
+SELECT id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at FROM scheduled_transfers
+WHERE from_account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListScheduledTransfersByAccountAfterParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	AfterID       int64 `json:"after_id"`
+	Limit         int32 `json:"limit"`
+}
+
+func (q *Queries) ListScheduledTransfersByAccountAfter(ctx context.Context, arg ListScheduledTransfersByAccountAfterParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfersByAccountAfter, arg.FromAccountID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.ScheduleUnit,
+			&i.ScheduleEvery,
+			&i.StartAt,
+			&i.EndAt,
+			&i.NextRunAt,
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateScheduledTransfer = `-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = COALESCE($1, amount),
+  end_at = CASE WHEN $2::boolean THEN NULL
+    ELSE COALESCE($3, end_at) END,
+  next_run_at = COALESCE($4, next_run_at),
+  active = COALESCE($5, active)
+WHERE id = $6
+RETURNING id, from_account_id, to_account_id, amount, schedule_unit, schedule_every, start_at, end_at, next_run_at, active, created_at
+`
+
+type UpdateScheduledTransferParams struct {
+	Amount     sql.NullInt64 `json:"amount"`
+	ClearEndAt bool          `json:"clear_end_at"`
+	EndAt      *time.Time    `json:"end_at"`
+	NextRunAt  sql.NullTime  `json:"next_run_at"`
+	Active     sql.NullBool  `json:"active"`
+	ID         int64         `json:"id"`
+}
+
+// Leaves every column whose argument is null as it is; clear_end_at removes
+// the end of the order.
+func (q *Queries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransfer,
+		arg.Amount,
+		arg.ClearEndAt,
+		arg.EndAt,
+		arg.NextRunAt,
+		arg.Active,
+		arg.ID,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.ScheduleUnit,
+		&i.ScheduleEvery,
+		&i.StartAt,
+		&i.EndAt,
+		&i.NextRunAt,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
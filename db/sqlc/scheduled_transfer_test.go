@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, account1, account2 Account, startAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		ScheduleUnit:  ScheduleUnitMonth,
+		ScheduleEvery: 1,
+		StartAt:       startAt,
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduled.ID)
+	require.Equal(t, arg.FromAccountID, scheduled.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduled.ToAccountID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.ScheduleUnit, scheduled.ScheduleUnit)
+	require.WithinDuration(t, startAt, scheduled.StartAt, time.Second)
+	require.WithinDuration(t, startAt, scheduled.NextRunAt, time.Second)
+	require.Nil(t, scheduled.EndAt)
+	require.True(t, scheduled.Active)
+
+	return scheduled
+}
+
+func TestScheduledTransferNextRunAfter(t *testing.T) {
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		unit      ScheduleUnit
+		every     int32
+		nextRunAt time.Time
+		now       time.Time
+		want      time.Time
+	}{
+		{
+			name:      "Daily",
+			unit:      ScheduleUnitDay,
+			every:     1,
+			nextRunAt: start,
+			now:       start,
+			want:      time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "EveryTwoWeeks",
+			unit:      ScheduleUnitWeek,
+			every:     2,
+			nextRunAt: start,
+			now:       start,
+			want:      time.Date(2024, 2, 14, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "MonthlyClampsToMonthEnd",
+			unit:      ScheduleUnitMonth,
+			every:     1,
+			nextRunAt: start,
+			now:       start,
+			want:      time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "MonthlyReturnsToStartDay",
+			unit:      ScheduleUnitMonth,
+			every:     1,
+			nextRunAt: time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+			now:       time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+			want:      time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "SkipsMissedRuns",
+			unit:      ScheduleUnitMonth,
+			every:     1,
+			nextRunAt: start,
+			now:       time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			want:      time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduled := ScheduledTransfer{
+				ScheduleUnit:  tc.unit,
+				ScheduleEvery: tc.every,
+				StartAt:       start,
+				NextRunAt:     tc.nextRunAt,
+			}
+			require.Equal(t, tc.want, scheduled.NextRunAfter(tc.now))
+		})
+	}
+}
+
+func TestRunScheduledTransferTx(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 15)
+	account2 := createRandomAccountWithCurrency(t, util.USD)
+
+	testStore := NewStore(testDB, testLogger)
+
+	// far in the past so it is the most overdue row
+	start := time.Date(1990, 1, 15, 0, 0, 0, 0, time.UTC)
+	scheduled := createRandomScheduledTransfer(t, account1, account2, start)
+	now := start.Add(time.Hour)
+
+	result, err := testStore.RunScheduledTransferTx(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ScheduledRunStatusSucceeded, result.Run.Status)
+	require.NotNil(t, result.Run.TransferID)
+	require.WithinDuration(t, start, result.Run.ScheduledFor, time.Second)
+	require.WithinDuration(t, start.AddDate(0, 1, 0), result.ScheduledTransfer.NextRunAt, time.Second)
+	require.True(t, result.ScheduledTransfer.Active)
+
+	account1, err = testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), account1.Balance)
+
+	// the second run cannot be covered; it is recorded and skipped
+	now = start.AddDate(0, 1, 0)
+	result, err = testStore.RunScheduledTransferTx(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ScheduledRunStatusFailed, result.Run.Status)
+	require.Nil(t, result.Run.TransferID)
+	require.Contains(t, result.Run.Error, "insufficient funds")
+	require.WithinDuration(t, start.AddDate(0, 2, 0), result.ScheduledTransfer.NextRunAt, time.Second)
+
+	runs, err := testStore.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	// the last run before the end date deactivates the order
+	endAt := start.AddDate(0, 2, 0)
+	_, err = testStore.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:    scheduled.ID,
+		EndAt: &endAt,
+	})
+	require.NoError(t, err)
+	fundAccount(t, account1, 100)
+
+	result, err = testStore.RunScheduledTransferTx(context.Background(), endAt)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ScheduledRunStatusSucceeded, result.Run.Status)
+	require.False(t, result.ScheduledTransfer.Active)
+
+	// clearing the end date lets the order be resumed
+	resumed, err := testStore.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:         scheduled.ID,
+		ClearEndAt: true,
+		Active:     sql.NullBool{Bool: true, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resumed.EndAt)
+	require.True(t, resumed.Active)
+	require.Equal(t, result.ScheduledTransfer.NextRunAt, resumed.NextRunAt)
+
+	err = testStore.DeleteScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	_, err = testStore.GetScheduledTransfer(context.Background(), scheduled.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRunScheduledTransferTxNothingDue(t *testing.T) {
+	testStore := NewStore(testDB, testLogger)
+
+	// nothing can be due before any scheduled transfer starts
+	_, err := testStore.RunScheduledTransferTx(context.Background(), time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestScheduledRunFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		failed bool
+	}{
+		{"InsufficientFunds", &InsufficientFundsError{AccountID: 1, Available: 5, Requested: 10}, true},
+		{"CurrencyMismatch", fmt.Errorf("%w: no quote for USD to EUR", ErrCurrencyMismatch), true},
+		{"MissingAccount", sql.ErrNoRows, true},
+		{"SameAccount", ErrSameAccount, true},
+		{"DroppedConnection", driver.ErrBadConn, false},
+		{"Cancelled", context.Canceled, false},
+		{"SerializationFailure", &pq.Error{Code: SerializationFailure}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.failed, scheduledRunFailed(tt.err))
+		})
+	}
+}
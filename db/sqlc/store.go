@@ -4,36 +4,110 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/NoahFola/simple_bank/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (HoldTxResult, error)
+	CaptureTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error)
+	VoidTransferTx(ctx context.Context, transferID int64) (HoldTxResult, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (AccountTxResult, error)
+	WithdrawTx(ctx context.Context, arg WithdrawTxParams) (AccountTxResult, error)
+	AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AccountTxResult, error)
+	ReconcileAccountTx(ctx context.Context, accountID int64) (AccountTxResult, error)
+	RunScheduledTransferTx(ctx context.Context, now time.Time) (RunScheduledTransferTxResult, error)
+	TxRetryStats() TxRetryStats
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (MigrationVersion, error)
 }
 
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
+
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	retries     txRetryCounters
 }
 
-func NewStore(db *sql.DB) Store {
+// NewStore creates a Store backed by db. Transactions log their steps to
+// logger at debug level; a nil logger discards them.
+func NewStore(db *sql.DB, logger *slog.Logger) Store {
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		db:          db,
+		logger:      logger,
+		Queries:     New(newTracedDB(db)),
+		maxAttempts: defaultTxMaxAttempts,
+		baseBackoff: defaultTxBaseBackoff,
+		maxBackoff:  defaultTxMaxBackoff,
+	}
+}
+
+// execTx runs fn in a transaction started with opts, which may be nil for
+// the defaults. A transaction that fails on a serialization failure or a
+// deadlock is rolled back and run again after a short random wait, up to
+// maxAttempts in all, so fn must not keep state between calls. Errors are
+// translated by TranslateError.
+func (store *SQLStore) execTx(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	for attempt := 1; ; attempt++ {
+		err := store.runTx(ctx, opts, fn)
+		if err == nil || !isRetryableTxError(err) {
+			return TranslateError(err)
+		}
+
+		if attempt >= store.maxAttempts {
+			store.retries.exhausted.Add(1)
+			return TranslateError(err)
+		}
+
+		store.txLogger(ctx).WarnContext(ctx, "retrying transaction", "attempt", attempt, "error", err)
+		trace.SpanFromContext(ctx).AddEvent("retrying transaction", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("error", err.Error()),
+		))
+
+		if waitErr := store.waitTxRetry(ctx, attempt); waitErr != nil {
+			return TranslateError(err)
+		}
+		store.retries.retries.Add(1)
+	}
+}
+
+// txLogger returns the store's logger, naming the transaction when the
+// context gives it a name.
+func (store *SQLStore) txLogger(ctx context.Context) *slog.Logger {
+	if txName, ok := ctx.Value(txKey).(string); ok && txName != "" {
+		return store.logger.With("tx", txName)
 	}
+	return store.logger
 }
 
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+func (store *SQLStore) runTx(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	q := New(tx)
+	q := New(newTracedDB(tx))
 	err = fn(q)
 	if err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
 		}
 		return err
 	}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The methods below shadow the queries promoted from Queries so that errors
+// returned outside a transaction are translated like those of the
+// transactions themselves. Every query must be listed here.
+
+func (store *SQLStore) AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error) {
+	account, err := store.Queries.AddAccountBalance(ctx, arg)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	account, err := store.Queries.AddAccountHeldBalance(ctx, arg)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error) {
+	scheduledTransfer, err := store.Queries.AdvanceScheduledTransfer(ctx, arg)
+	return scheduledTransfer, TranslateError(err)
+}
+
+func (store *SQLStore) BlockUserSessions(ctx context.Context, username string) (int64, error) {
+	n, err := store.Queries.BlockUserSessions(ctx, username)
+	return n, TranslateError(err)
+}
+
+func (store *SQLStore) ClaimDueScheduledTransfer(ctx context.Context, now time.Time) (ScheduledTransfer, error) {
+	scheduledTransfer, err := store.Queries.ClaimDueScheduledTransfer(ctx, now)
+	return scheduledTransfer, TranslateError(err)
+}
+
+func (store *SQLStore) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error) {
+	n, err := store.Queries.ClaimIdempotencyKey(ctx, arg)
+	return n, TranslateError(err)
+}
+
+func (store *SQLStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	account, err := store.Queries.CreateAccount(ctx, arg)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	entry, err := store.Queries.CreateEntry(ctx, arg)
+	return entry, TranslateError(err)
+}
+
+func (store *SQLStore) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	hold, err := store.Queries.CreateHold(ctx, arg)
+	return hold, TranslateError(err)
+}
+
+func (store *SQLStore) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	scheduledTransfer, err := store.Queries.CreateScheduledTransfer(ctx, arg)
+	return scheduledTransfer, TranslateError(err)
+}
+
+func (store *SQLStore) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	scheduledTransferRun, err := store.Queries.CreateScheduledTransferRun(ctx, arg)
+	return scheduledTransferRun, TranslateError(err)
+}
+
+func (store *SQLStore) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	session, err := store.Queries.CreateSession(ctx, arg)
+	return session, TranslateError(err)
+}
+
+func (store *SQLStore) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	transfer, err := store.Queries.CreateTransfer(ctx, arg)
+	return transfer, TranslateError(err)
+}
+
+func (store *SQLStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	user, err := store.Queries.CreateUser(ctx, arg)
+	return user, TranslateError(err)
+}
+
+func (store *SQLStore) DeleteAccount(ctx context.Context, id int64) error {
+	return TranslateError(store.Queries.DeleteAccount(ctx, id))
+}
+
+func (store *SQLStore) DeleteScheduledTransfer(ctx context.Context, id int64) error {
+	return TranslateError(store.Queries.DeleteScheduledTransfer(ctx, id))
+}
+
+func (store *SQLStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	account, err := store.Queries.GetAccount(ctx, id)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) GetAccountEntriesTotal(ctx context.Context, accountID int64) (int64, error) {
+	total, err := store.Queries.GetAccountEntriesTotal(ctx, accountID)
+	return total, TranslateError(err)
+}
+
+func (store *SQLStore) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	account, err := store.Queries.GetAccountForUpdate(ctx, id)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	entry, err := store.Queries.GetEntry(ctx, id)
+	return entry, TranslateError(err)
+}
+
+func (store *SQLStore) GetHoldByTransfer(ctx context.Context, transferID int64) (Hold, error) {
+	hold, err := store.Queries.GetHoldByTransfer(ctx, transferID)
+	return hold, TranslateError(err)
+}
+
+func (store *SQLStore) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	idempotencyKey, err := store.Queries.GetIdempotencyKey(ctx, arg)
+	return idempotencyKey, TranslateError(err)
+}
+
+func (store *SQLStore) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	scheduledTransfer, err := store.Queries.GetScheduledTransfer(ctx, id)
+	return scheduledTransfer, TranslateError(err)
+}
+
+func (store *SQLStore) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	session, err := store.Queries.GetSession(ctx, id)
+	return session, TranslateError(err)
+}
+
+func (store *SQLStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	transfer, err := store.Queries.GetTransfer(ctx, id)
+	return transfer, TranslateError(err)
+}
+
+func (store *SQLStore) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	transfer, err := store.Queries.GetTransferForUpdate(ctx, id)
+	return transfer, TranslateError(err)
+}
+
+func (store *SQLStore) GetTransferReversedTotals(ctx context.Context, transferID int64) (GetTransferReversedTotalsRow, error) {
+	row, err := store.Queries.GetTransferReversedTotals(ctx, transferID)
+	return row, TranslateError(err)
+}
+
+func (store *SQLStore) GetUser(ctx context.Context, username string) (User, error) {
+	user, err := store.Queries.GetUser(ctx, username)
+	return user, TranslateError(err)
+}
+
+func (store *SQLStore) ListAccountLedgerTotalsAfter(ctx context.Context, arg ListAccountLedgerTotalsAfterParams) ([]ListAccountLedgerTotalsAfterRow, error) {
+	rows, err := store.Queries.ListAccountLedgerTotalsAfter(ctx, arg)
+	return rows, TranslateError(err)
+}
+
+func (store *SQLStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	accounts, err := store.Queries.ListAccounts(ctx, arg)
+	return accounts, TranslateError(err)
+}
+
+func (store *SQLStore) ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error) {
+	accounts, err := store.Queries.ListAccountsByOwner(ctx, arg)
+	return accounts, TranslateError(err)
+}
+
+func (store *SQLStore) ListAccountsByOwnerAfter(ctx context.Context, arg ListAccountsByOwnerAfterParams) ([]Account, error) {
+	accounts, err := store.Queries.ListAccountsByOwnerAfter(ctx, arg)
+	return accounts, TranslateError(err)
+}
+
+func (store *SQLStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	entries, err := store.Queries.ListEntries(ctx, arg)
+	return entries, TranslateError(err)
+}
+
+func (store *SQLStore) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error) {
+	entries, err := store.Queries.ListEntriesByAccount(ctx, arg)
+	return entries, TranslateError(err)
+}
+
+func (store *SQLStore) ListEntriesByAccountAfter(ctx context.Context, arg ListEntriesByAccountAfterParams) ([]Entry, error) {
+	entries, err := store.Queries.ListEntriesByAccountAfter(ctx, arg)
+	return entries, TranslateError(err)
+}
+
+func (store *SQLStore) ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error) {
+	holds, err := store.Queries.ListExpiredHolds(ctx, arg)
+	return holds, TranslateError(err)
+}
+
+func (store *SQLStore) ListOrphanEntriesAfter(ctx context.Context, arg ListOrphanEntriesAfterParams) ([]Entry, error) {
+	entries, err := store.Queries.ListOrphanEntriesAfter(ctx, arg)
+	return entries, TranslateError(err)
+}
+
+func (store *SQLStore) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	scheduledTransferRuns, err := store.Queries.ListScheduledTransferRuns(ctx, arg)
+	return scheduledTransferRuns, TranslateError(err)
+}
+
+func (store *SQLStore) ListScheduledTransfersByAccount(ctx context.Context, arg ListScheduledTransfersByAccountParams) ([]ScheduledTransfer, error) {
+	scheduledTransfers, err := store.Queries.ListScheduledTransfersByAccount(ctx, arg)
+	return scheduledTransfers, TranslateError(err)
+}
+
+func (store *SQLStore) ListScheduledTransfersByAccountAfter(ctx context.Context, arg ListScheduledTransfersByAccountAfterParams) ([]ScheduledTransfer, error) {
+	scheduledTransfers, err := store.Queries.ListScheduledTransfersByAccountAfter(ctx, arg)
+	return scheduledTransfers, TranslateError(err)
+}
+
+func (store *SQLStore) ListTransferEntryCountsAfter(ctx context.Context, arg ListTransferEntryCountsAfterParams) ([]ListTransferEntryCountsAfterRow, error) {
+	rows, err := store.Queries.ListTransferEntryCountsAfter(ctx, arg)
+	return rows, TranslateError(err)
+}
+
+func (store *SQLStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	transfers, err := store.Queries.ListTransfers(ctx, arg)
+	return transfers, TranslateError(err)
+}
+
+func (store *SQLStore) ListTransfersByAccount(ctx context.Context, arg ListTransfersByAccountParams) ([]Transfer, error) {
+	transfers, err := store.Queries.ListTransfersByAccount(ctx, arg)
+	return transfers, TranslateError(err)
+}
+
+func (store *SQLStore) ListTransfersByAccountAfter(ctx context.Context, arg ListTransfersByAccountAfterParams) ([]Transfer, error) {
+	transfers, err := store.Queries.ListTransfersByAccountAfter(ctx, arg)
+	return transfers, TranslateError(err)
+}
+
+func (store *SQLStore) ReleaseHold(ctx context.Context, id int64) (Hold, error) {
+	hold, err := store.Queries.ReleaseHold(ctx, id)
+	return hold, TranslateError(err)
+}
+
+func (store *SQLStore) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	account, err := store.Queries.UpdateAccount(ctx, arg)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	account, err := store.Queries.UpdateAccountOverdraftLimit(ctx, arg)
+	return account, TranslateError(err)
+}
+
+func (store *SQLStore) UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) error {
+	return TranslateError(store.Queries.UpdateIdempotencyKeyResponse(ctx, arg))
+}
+
+func (store *SQLStore) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	scheduledTransfer, err := store.Queries.UpdateScheduledTransfer(ctx, arg)
+	return scheduledTransfer, TranslateError(err)
+}
+
+func (store *SQLStore) UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error) {
+	transfer, err := store.Queries.UpdateTransferStatus(ctx, arg)
+	return transfer, TranslateError(err)
+}
@@ -4,20 +4,34 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/NoahFola/simple_bank/util"
 	"github.com/stretchr/testify/require"
 )
 
-func TestTransferTx(t *testing.T) {
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
-	fmt.Println(">> before:", account1.Balance, account2.Balance)
-
-	testStore := NewStore(testDB)
+// fundAccount sets the balance of an account so transfers out of it
+// cannot fail on insufficient funds.
+func fundAccount(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	return account
+}
 
+func TestTransferTx(t *testing.T) {
 	n := 24
 	amount := int64(10)
 
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), int64(n)*amount)
+	account2 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), int64(n)*amount)
+	fmt.Println(">> before:", account1.Balance, account2.Balance)
+
+	testStore := NewStore(testDB, testLogger)
+
 	errs := make(chan error)
 	// results := make(chan TransferTxResult)
 
@@ -101,3 +115,598 @@ func TestTransferTx(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 50)
+	account2 := createRandomAccountWithCurrency(t, util.USD)
+
+	testStore := NewStore(testDB, testLogger)
+
+	_, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        51,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var fundsErr *InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	require.Equal(t, account1.ID, fundsErr.AccountID)
+	require.Equal(t, int64(50), fundsErr.Available)
+	require.Equal(t, int64(51), fundsErr.Requested)
+
+	// the transaction must be rolled back, leaving no trace of the transfer
+	var count int
+	err = testDB.QueryRow("SELECT count(*) FROM entries WHERE account_id = $1 OR account_id = $2",
+		account1.ID, account2.ID).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	err = testDB.QueryRow("SELECT count(*) FROM transfers WHERE from_account_id = $1",
+		account1.ID).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testStore.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxSameAccount(t *testing.T) {
+	account := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 50)
+
+	testStore := NewStore(testDB, testLogger)
+
+	_, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrSameAccount)
+	require.Equal(t, apperr.InvalidArgument, apperr.CodeOf(err))
+
+	_, err = testStore.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrSameAccount)
+
+	unchanged, err := testStore.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 50)
+	account2 := createRandomAccountWithCurrency(t, util.USD)
+
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 100,
+	})
+	require.NoError(t, err)
+
+	testStore := NewStore(testDB, testLogger)
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        150,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-100), result.FromAccount.Balance)
+
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 1000)
+	account2 := fundAccount(t, createRandomAccountWithCurrency(t, util.EUR), 0)
+
+	testStore := NewStore(testDB, testLogger)
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		Quote:         util.FXQuote{From: util.USD, To: util.EUR, Rate: "0.925"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(100), result.Transfer.Amount)
+	require.Equal(t, int64(93), result.Transfer.ToAmount)
+	require.Equal(t, "0.925", result.Transfer.ExchangeRate)
+
+	require.Equal(t, int64(-100), result.FromEntry.Amount)
+	require.Equal(t, int64(93), result.ToEntry.Amount)
+
+	require.Equal(t, EntryTypeTransferDebit, result.FromEntry.EntryType)
+	require.Equal(t, EntryTypeTransferCredit, result.ToEntry.EntryType)
+	require.NotNil(t, result.FromEntry.TransferID)
+	require.Equal(t, result.Transfer.ID, *result.FromEntry.TransferID)
+	require.NotNil(t, result.ToEntry.TransferID)
+	require.Equal(t, result.Transfer.ID, *result.ToEntry.TransferID)
+	require.Equal(t, int64(900), result.FromAccount.Balance)
+	require.Equal(t, int64(93), result.ToAccount.Balance)
+
+	// without a quote the accounts must share a currency
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// a quote for the wrong pair is rejected as well
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		Quote:         util.FXQuote{From: util.USD, To: util.CAD, Rate: "1.36"},
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// an amount that rounds to nothing in the destination currency is the
+	// client's mistake, not a server error
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+		Quote:         util.FXQuote{From: util.USD, To: util.EUR, Rate: "0.001"},
+	})
+	require.Equal(t, apperr.Unprocessable, apperr.CodeOf(err))
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 1000)
+	account2 := fundAccount(t, createRandomAccountWithCurrency(t, util.EUR), 0)
+
+	testStore := NewStore(testDB, testLogger)
+
+	original, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		Quote:         util.FXQuote{From: util.USD, To: util.EUR, Rate: "0.925"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusSettled, original.Transfer.Status)
+	require.Equal(t, int64(93), original.Transfer.ToAmount)
+
+	// partial refund of 40 USD takes back 37 EUR at the original rate
+	partial, err := testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusPartiallyReversed, partial.OriginalTransfer.Status)
+	require.NotNil(t, partial.Transfer.ReversalOf)
+	require.Equal(t, original.Transfer.ID, *partial.Transfer.ReversalOf)
+	require.Equal(t, account2.ID, partial.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, partial.Transfer.ToAccountID)
+	require.Equal(t, int64(37), partial.Transfer.Amount)
+	require.Equal(t, int64(40), partial.Transfer.ToAmount)
+	require.Equal(t, int64(960), partial.ToAccount.Balance)
+	require.Equal(t, int64(56), partial.FromAccount.Balance)
+
+	// refunds never add up to more than the original amount
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     61,
+	})
+	require.ErrorIs(t, err, ErrInvalidReversalAmount)
+
+	// the final refund takes back whatever is left, rounding included
+	full, err := testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusReversed, full.OriginalTransfer.Status)
+	require.Equal(t, int64(56), full.Transfer.Amount)
+	require.Equal(t, int64(60), full.Transfer.ToAmount)
+	require.Equal(t, account1.Balance, full.ToAccount.Balance)
+	require.Equal(t, account2.Balance, full.FromAccount.Balance)
+
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+
+	// a reversal cannot itself be reversed
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: full.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	account2 := createRandomAccountWithCurrency(t, util.USD)
+
+	testStore := NewStore(testDB, testLogger)
+
+	arg := TransferTxParams{
+		FromAccountID:       account1.ID,
+		ToAccountID:         account2.ID,
+		Amount:              10,
+		IdempotencyKey:      util.RandomString(32),
+		IdempotencyKeyOwner: account1.Owner,
+		IdempotencyKeyTTL:   time.Hour,
+	}
+
+	result1, err := testStore.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// a replay with the same key and payload returns the original result
+	result2, err := testStore.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, result1.Transfer.ID, result2.Transfer.ID)
+	require.Equal(t, result1.FromAccount.Balance, result2.FromAccount.Balance)
+
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-arg.Amount, updatedAccount1.Balance)
+
+	// another user's key of the same name is a separate key
+	account3 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	other := arg
+	other.FromAccountID = account3.ID
+	other.IdempotencyKeyOwner = account3.Owner
+	result3, err := testStore.TransferTx(context.Background(), other)
+	require.NoError(t, err)
+	require.NotEqual(t, result1.Transfer.ID, result3.Transfer.ID)
+	require.Equal(t, account3.ID, result3.Transfer.FromAccountID)
+
+	// a replay with a different payload is rejected
+	arg.Amount = 20
+	_, err = testStore.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+}
+
+func TestTransferTxIdempotencyKeyConcurrent(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	account2 := createRandomAccountWithCurrency(t, util.USD)
+
+	testStore := NewStore(testDB, testLogger)
+
+	arg := TransferTxParams{
+		FromAccountID:       account1.ID,
+		ToAccountID:         account2.ID,
+		Amount:              10,
+		IdempotencyKey:      util.RandomString(32),
+		IdempotencyKeyOwner: account1.Owner,
+		IdempotencyKeyTTL:   time.Hour,
+	}
+
+	n := 5
+	results := make(chan TransferTxResult)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := testStore.TransferTx(context.Background(), arg)
+			errs <- err
+			results <- result
+		}()
+	}
+
+	transferIDs := make(map[int64]bool)
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		transferIDs[(<-results).Transfer.ID] = true
+	}
+	require.Len(t, transferIDs, 1)
+
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-arg.Amount, updatedAccount1.Balance)
+}
+
+func TestDepositAndWithdrawTx(t *testing.T) {
+	account := fundAccount(t, createRandomAccount(t), 100)
+	testStore := NewStore(testDB, testLogger)
+
+	deposit, err := testStore.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(150), deposit.Account.Balance)
+	require.Equal(t, account.ID, deposit.Entry.AccountID)
+	require.Equal(t, int64(50), deposit.Entry.Amount)
+	require.Equal(t, EntryTypeDeposit, deposit.Entry.EntryType)
+
+	withdrawal, err := testStore.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    120,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), withdrawal.Account.Balance)
+	require.Equal(t, int64(-120), withdrawal.Entry.Amount)
+	require.Equal(t, EntryTypeWithdrawal, withdrawal.Entry.EntryType)
+
+	_, err = testStore.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    31,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	account, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(30), account.Balance)
+}
+
+func TestAdjustBalanceTx(t *testing.T) {
+	account := fundAccount(t, createRandomAccount(t), 100)
+	testStore := NewStore(testDB, testLogger)
+
+	result, err := testStore.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Balance:   40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(40), result.Account.Balance)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, int64(-60), result.Entry.Amount)
+	require.Equal(t, EntryTypeAdjustment, result.Entry.EntryType)
+
+	// setting the balance it already has writes no entry
+	result, err = testStore.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Balance:   40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(40), result.Account.Balance)
+	require.Zero(t, result.Entry.ID)
+}
+
+func TestAuthorizeAndCaptureTransferTx(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	account2 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 0)
+
+	testStore := NewStore(testDB, testLogger)
+
+	auth, err := testStore.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+		HoldDuration:  time.Hour,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusAuthorized, auth.Transfer.Status)
+	require.Equal(t, auth.Transfer.ID, auth.Hold.TransferID)
+	require.Equal(t, int64(60), auth.Hold.Amount)
+	require.False(t, auth.Hold.ReleasedAt.Valid)
+
+	// the hold reduces the available balance but not the ledger balance
+	require.Equal(t, int64(100), auth.FromAccount.Balance)
+	require.Equal(t, int64(60), auth.FromAccount.HeldBalance)
+	require.Equal(t, int64(40), auth.FromAccount.AvailableBalance)
+
+	// money on hold cannot be spent twice
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        50,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// an authorization has no entries and cannot be reversed
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: auth.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+
+	captured, err := testStore.CaptureTransferTx(context.Background(), auth.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusSettled, captured.Transfer.Status)
+	require.Equal(t, int64(-60), captured.FromEntry.Amount)
+	require.Equal(t, int64(60), captured.ToEntry.Amount)
+	require.Equal(t, int64(40), captured.FromAccount.Balance)
+	require.Zero(t, captured.FromAccount.HeldBalance)
+	require.Equal(t, int64(40), captured.FromAccount.AvailableBalance)
+	require.Equal(t, int64(60), captured.ToAccount.Balance)
+
+	hold, err := testStore.GetHoldByTransfer(context.Background(), auth.Transfer.ID)
+	require.NoError(t, err)
+	require.True(t, hold.ReleasedAt.Valid)
+
+	_, err = testStore.CaptureTransferTx(context.Background(), auth.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotAuthorized)
+	_, err = testStore.VoidTransferTx(context.Background(), auth.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotAuthorized)
+}
+
+func TestVoidAndExpireTransferTx(t *testing.T) {
+	account1 := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	account2 := createRandomAccountWithCurrency(t, util.USD)
+
+	testStore := NewStore(testDB, testLogger)
+
+	_, err := testStore.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        101,
+		HoldDuration:  time.Hour,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	auth, err := testStore.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        30,
+		HoldDuration:  time.Hour,
+	})
+	require.NoError(t, err)
+
+	voided, err := testStore.VoidTransferTx(context.Background(), auth.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusFailed, voided.Transfer.Status)
+	require.True(t, voided.Hold.ReleasedAt.Valid)
+	require.Equal(t, int64(100), voided.FromAccount.Balance)
+	require.Equal(t, int64(100), voided.FromAccount.AvailableBalance)
+
+	// a lapsed hold can no longer be captured and is listed for expiry
+	expiring, err := testStore.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        30,
+		HoldDuration:  -time.Second,
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.CaptureTransferTx(context.Background(), expiring.Transfer.ID)
+	require.ErrorIs(t, err, ErrHoldExpired)
+
+	holds, err := testStore.ListExpiredHolds(context.Background(), ListExpiredHoldsParams{
+		ExpiredAt: time.Now(),
+		Limit:     1000,
+	})
+	require.NoError(t, err)
+
+	var found bool
+	for _, hold := range holds {
+		if hold.TransferID == expiring.Transfer.ID {
+			found = true
+		}
+		require.NotEqual(t, auth.Transfer.ID, hold.TransferID)
+	}
+	require.True(t, found)
+}
+
+func TestBatchTransferTxBestEffort(t *testing.T) {
+	funding := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	payee1 := createRandomAccountWithCurrency(t, util.USD)
+	payee2 := createRandomAccountWithCurrency(t, util.USD)
+	foreign := createRandomAccountWithCurrency(t, util.EUR)
+
+	testStore := NewStore(testDB, testLogger)
+
+	result, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: funding.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: payee1.ID, Amount: 40},
+			{ToAccountID: foreign.ID, Amount: 10},
+			{ToAccountID: payee2.ID, Amount: 70},
+			{ToAccountID: payee1.ID, Amount: 60},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 4)
+
+	require.NotNil(t, result.Items[0].Transfer)
+	require.Empty(t, result.Items[0].Error)
+	require.Equal(t, payee1.ID, result.Items[0].Transfer.ToAccountID)
+
+	require.Nil(t, result.Items[1].Transfer)
+	require.Contains(t, result.Items[1].Error, ErrCurrencyMismatch.Error())
+
+	// only 60 is left after the first item
+	require.Nil(t, result.Items[2].Transfer)
+	require.Contains(t, result.Items[2].Error, ErrInsufficientFunds.Error())
+
+	require.NotNil(t, result.Items[3].Transfer)
+	require.Zero(t, result.FromAccount.Balance)
+
+	payee1, err = testStore.GetAccount(context.Background(), payee1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), payee1.Balance)
+
+	payee2, err = testStore.GetAccount(context.Background(), payee2.ID)
+	require.NoError(t, err)
+	require.Zero(t, payee2.Balance)
+
+	total, err := testStore.GetAccountEntriesTotal(context.Background(), funding.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-100), total)
+}
+
+func TestBatchTransferTxAllOrNothing(t *testing.T) {
+	funding := fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 100)
+	payee1 := createRandomAccountWithCurrency(t, util.USD)
+	payee2 := createRandomAccountWithCurrency(t, util.USD)
+
+	testStore := NewStore(testDB, testLogger)
+
+	_, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: funding.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: payee1.ID, Amount: 40},
+			{ToAccountID: payee2.ID, Amount: 70},
+		},
+		AllOrNothing: true,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var itemErr *BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	require.Equal(t, 1, itemErr.Index)
+
+	// nothing from the batch was kept
+	payee1, err = testStore.GetAccount(context.Background(), payee1.ID)
+	require.NoError(t, err)
+	require.Zero(t, payee1.Balance)
+
+	result, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: funding.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: payee1.ID, Amount: 40},
+			{ToAccountID: payee2.ID, Amount: 60},
+		},
+		AllOrNothing: true,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccount.Balance)
+	for _, item := range result.Items {
+		require.NotNil(t, item.Transfer)
+		require.Equal(t, TransferStatusSettled, item.Transfer.Status)
+	}
+}
+
+func TestBatchTransferTxConcurrent(t *testing.T) {
+	n := 10
+	accounts := make([]Account, 3)
+	for i := range accounts {
+		accounts[i] = fundAccount(t, createRandomAccountWithCurrency(t, util.USD), 1000)
+	}
+
+	testStore := NewStore(testDB, testLogger)
+	errs := make(chan error)
+
+	// batches funded from each account pay the other two, so every batch
+	// locks the same accounts and would deadlock if the order differed
+	for i := 0; i < n; i++ {
+		from := accounts[i%3]
+		items := []BatchTransferItem{
+			{ToAccountID: accounts[(i+2)%3].ID, Amount: 10},
+			{ToAccountID: accounts[(i+1)%3].ID, Amount: 10},
+		}
+		go func() {
+			_, err := testStore.BatchTransferTx(context.Background(), BatchTransferTxParams{
+				FromAccountID: from.ID,
+				Items:         items,
+				AllOrNothing:  true,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	var total int64
+	for _, account := range accounts {
+		updated, err := testStore.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		total += updated.Balance
+	}
+	require.Equal(t, int64(3000), total)
+}
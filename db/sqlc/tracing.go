@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName names the tracer of the store's spans
+const TracerName = "github.com/NoahFola/simple_bank/db/sqlc"
+
+// tracedDB is a DBTX that records a span for each statement, named after
+// the sqlc query it runs. Arguments are not recorded as they may hold
+// personal data.
+type tracedDB struct {
+	db     DBTX
+	tracer trace.Tracer
+}
+
+func newTracedDB(db DBTX) *tracedDB {
+	return &tracedDB{db: db, tracer: otel.Tracer(TracerName)}
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	recordSpanError(span, err)
+	return result, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordSpanError(span, err)
+	return stmt, err
+}
+
+// QueryContext records the time to run the query; reading the rows is not
+// part of the span.
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordSpanError(span, err)
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+	recordSpanError(span, row.Err())
+	return row
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := queryName(query)
+	return t.tracer.Start(ctx, "db."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.operation.name", name),
+		),
+	)
+}
+
+// queryName returns the name sqlc gives the query in its leading
+// "-- name: X :kind" comment, or "query" for statements without one.
+func queryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "query"
+	}
+
+	name, _, _ := strings.Cut(query[len(prefix):], " ")
+	return name
+}
+
+// recordSpanError marks span as failed with err, if any
+func recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// failingDBTX fails every statement with err
+type failingDBTX struct {
+	err error
+}
+
+func (f failingDBTX) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, f.err
+}
+
+func (f failingDBTX) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, f.err
+}
+
+func (f failingDBTX) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, f.err
+}
+
+func (f failingDBTX) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	panic("not used")
+}
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccountForUpdate", queryName(getAccountForUpdate))
+	require.Equal(t, "query", queryName("SELECT 1"))
+}
+
+func TestTracedDB(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	traced := &tracedDB{db: failingDBTX{err: sql.ErrConnDone}, tracer: provider.Tracer(TracerName)}
+	q := New(traced)
+
+	err := q.DeleteAccount(context.Background(), 1)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	_, err = q.ListAccounts(context.Background(), ListAccountsParams{Limit: 5})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "db.DeleteAccount", spans[0].Name())
+	require.Equal(t, "db.ListAccounts", spans[1].Name())
+	for _, span := range spans {
+		require.Equal(t, codes.Error, span.Status().Code)
+		require.Len(t, span.Events(), 1)
+	}
+}
@@ -7,24 +7,37 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
-  from_account_id, to_account_id, amount
+  from_account_id, to_account_id, amount, to_amount, exchange_rate, reversal_of, status
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	ToAmount      int64          `json:"to_amount"`
+	ExchangeRate  string         `json:"exchange_rate"`
+	ReversalOf    *int64         `json:"reversal_of"`
+	Status        TransferStatus `json:"status"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.ReversalOf,
+		arg.Status,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +45,16 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -50,12 +67,116 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
 	)
 	return i, err
 }
 
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+	)
+	return i, err
+}
+
+const getTransferReversedTotals = `-- name: GetTransferReversedTotals :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS debited,
+  COALESCE(SUM(to_amount), 0)::bigint AS refunded
+FROM transfers
+WHERE reversal_of = $1::bigint
+`
+
+type GetTransferReversedTotalsRow struct {
+	Debited  int64 `json:"debited"`
+	Refunded int64 `json:"refunded"`
+}
+
+func (q *Queries) GetTransferReversedTotals(ctx context.Context, transferID int64) (GetTransferReversedTotalsRow, error) {
+	row := q.db.QueryRowContext(ctx, getTransferReversedTotals, transferID)
+	var i GetTransferReversedTotalsRow
+	err := row.Scan(&i.Debited, &i.Refunded)
+	return i, err
+}
+
+const listTransferEntryCountsAfter = `-- name: ListTransferEntryCountsAfter :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.status,
+  (SELECT count(*) FROM entries e
+    WHERE e.transfer_id = t.id AND e.entry_type = 'transfer_debit'
+      AND e.account_id = t.from_account_id AND e.amount = -t.amount) AS debit_entries,
+  (SELECT count(*) FROM entries e
+    WHERE e.transfer_id = t.id AND e.entry_type = 'transfer_credit'
+      AND e.account_id = t.to_account_id AND e.amount = t.to_amount) AS credit_entries
+FROM transfers t
+WHERE t.id > $1
+ORDER BY t.id
+LIMIT $2
+`
+
+type ListTransferEntryCountsAfterParams struct {
+	AfterID int64 `json:"after_id"`
+	Limit   int32 `json:"limit"`
+}
+
+type ListTransferEntryCountsAfterRow struct {
+	ID            int64          `json:"id"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Status        TransferStatus `json:"status"`
+	DebitEntries  int64          `json:"debit_entries"`
+	CreditEntries int64          `json:"credit_entries"`
+}
+
+func (q *Queries) ListTransferEntryCountsAfter(ctx context.Context, arg ListTransferEntryCountsAfterParams) ([]ListTransferEntryCountsAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntryCountsAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferEntryCountsAfterRow{}
+	for rows.Next() {
+		var i ListTransferEntryCountsAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Status,
+			&i.DebitEntries,
+			&i.CreditEntries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -81,6 +202,130 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersByAccount = `-- name: ListTransfersByAccount :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $2)
+  AND ($3::timestamptz IS NULL OR created_at >= $3)
+  AND ($4::timestamptz IS NULL OR created_at < $4)
+ORDER BY id
+LIMIT $6
+OFFSET $5
+`
+
+type ListTransfersByAccountParams struct {
+	FromAccountID sql.NullInt64 `json:"from_account_id"`
+	ToAccountID   sql.NullInt64 `json:"to_account_id"`
+	StartTime     sql.NullTime  `json:"start_time"`
+	EndTime       sql.NullTime  `json:"end_time"`
+	Offset        int32         `json:"offset"`
+	Limit         int32         `json:"limit"`
+}
+
+// Pass the account ID as from_account_id for outgoing transfers, as
+// to_account_id for incoming ones, or as both for either direction.
+func (q *Queries) ListTransfersByAccount(ctx context.Context, arg ListTransfersByAccountParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersByAccount,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersByAccountAfter = `-- name: ListTransfersByAccountAfter :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $2)
+  AND id > $3
+  AND ($4::timestamptz IS NULL OR created_at >= $4)
+  AND ($5::timestamptz IS NULL OR created_at < $5)
+ORDER BY id
+LIMIT $6
+`
+
+type ListTransfersByAccountAfterParams struct {
+	FromAccountID sql.NullInt64 `json:"from_account_id"`
+	ToAccountID   sql.NullInt64 `json:"to_account_id"`
+	AfterID       int64         `json:"after_id"`
+	StartTime     sql.NullTime  `json:"start_time"`
+	EndTime       sql.NullTime  `json:"end_time"`
+	Limit         int32         `json:"limit"`
+}
+
+func (q *Queries) ListTransfersByAccountAfter(ctx context.Context, arg ListTransfersByAccountAfterParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersByAccountAfter,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.AfterID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
 		); err != nil {
 			return nil, err
 		}
@@ -94,3 +339,32 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const updateTransferStatus = `-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET status = $2
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of
+`
+
+type UpdateTransferStatusParams struct {
+	ID     int64          `json:"id"`
+	Status TransferStatus `json:"status"`
+}
+
+func (q *Queries) UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, updateTransferStatus, arg.ID, arg.Status)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+	)
+	return i, err
+}
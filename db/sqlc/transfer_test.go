@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -14,7 +15,10 @@ func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
 		Amount:        util.RandomMoney(),
+		ExchangeRate:  "1",
+		Status:        TransferStatusSettled,
 	}
+	arg.ToAmount = arg.Amount
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 	require.NoError(t, err)
@@ -23,6 +27,9 @@ func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.Status, transfer.Status)
+	require.Nil(t, transfer.ReversalOf)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -74,3 +81,68 @@ func TestListTransfer(t *testing.T) {
 		require.NotEmpty(t, transfer)
 	}
 }
+
+func TestListTransfersByAccount(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	for i := 0; i < 3; i++ {
+		createRandomTransfer(t, account1, account2)
+	}
+	for i := 0; i < 2; i++ {
+		createRandomTransfer(t, account2, account1)
+	}
+
+	accountID := sql.NullInt64{Int64: account1.ID, Valid: true}
+
+	outgoing, err := testQueries.ListTransfersByAccount(context.Background(), ListTransfersByAccountParams{
+		FromAccountID: accountID,
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, outgoing, 3)
+	for _, transfer := range outgoing {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
+
+	incoming, err := testQueries.ListTransfersByAccount(context.Background(), ListTransfersByAccountParams{
+		ToAccountID: accountID,
+		Limit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, incoming, 2)
+	for _, transfer := range incoming {
+		require.Equal(t, account1.ID, transfer.ToAccountID)
+	}
+
+	both, err := testQueries.ListTransfersByAccount(context.Background(), ListTransfersByAccountParams{
+		FromAccountID: accountID,
+		ToAccountID:   accountID,
+		StartTime:     sql.NullTime{Time: outgoing[0].CreatedAt, Valid: true},
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, both, 5)
+}
+
+func TestListTransfersByAccountAfter(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	var created []Transfer
+	for i := 0; i < 4; i++ {
+		created = append(created, createRandomTransfer(t, account1, account2))
+	}
+
+	accountID := sql.NullInt64{Int64: account2.ID, Valid: true}
+	transfers, err := testQueries.ListTransfersByAccountAfter(context.Background(), ListTransfersByAccountAfterParams{
+		FromAccountID: accountID,
+		ToAccountID:   accountID,
+		AfterID:       created[0].ID,
+		Limit:         2,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	require.Equal(t, created[1].ID, transfers[0].ID)
+	require.Equal(t, created[2].ID, transfers[1].ID)
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+)
+
+type DepositTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+type WithdrawTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+type AdjustBalanceTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Balance   int64 `json:"balance"`
+}
+
+// AccountTxResult is the outcome of a single-account balance change: the
+// updated account and the entry recording the change.
+type AccountTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx credits an account and records the matching entry
+func (store *SQLStore) DepositTx(ctx context.Context, arg DepositTxParams) (AccountTxResult, error) {
+	var result AccountTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			EntryType: EntryTypeDeposit,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// WithdrawTx debits an account and records the matching entry, refusing to
+// take the available balance below the account's overdraft limit.
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg WithdrawTxParams) (AccountTxResult, error) {
+	var result AccountTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkFunds(ctx, store.txLogger(ctx), account, arg.Amount); err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    -arg.Amount,
+			EntryType: EntryTypeWithdrawal,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: -arg.Amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// AdjustBalanceTx sets an account's balance outright, recording the
+// difference from the previous balance as an entry so the ledger still sums
+// to the balance. Result.Entry is left empty when the balance is unchanged.
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AccountTxResult, error) {
+	var result AccountTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+		// clear an entry left by an attempt that was retried
+		result = AccountTxResult{}
+
+		result.Account, err = q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		delta := arg.Balance - result.Account.Balance
+		if delta == 0 {
+			return nil
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    delta,
+			EntryType: EntryTypeAdjustment,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.UpdateAccount(ctx, UpdateAccountParams{
+			ID:      arg.AccountID,
+			Balance: arg.Balance,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// ReconcileAccountTx writes an entry for any difference between an
+// account's balance and the sum of its entries, treating the balance as
+// correct. The account is locked while the entries are summed so a
+// concurrent transfer cannot skew the result. Result.Entry is left empty
+// when the ledger already matches.
+func (store *SQLStore) ReconcileAccountTx(ctx context.Context, accountID int64) (AccountTxResult, error) {
+	var result AccountTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		var err error
+		// clear an entry left by an attempt that was retried
+		result = AccountTxResult{}
+
+		result.Account, err = q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		total, err := q.GetAccountEntriesTotal(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		if total == result.Account.Balance {
+			return nil
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: accountID,
+			Amount:    result.Account.Balance - total,
+			EntryType: EntryTypeAdjustment,
+		})
+		return err
+	})
+
+	return result, err
+}
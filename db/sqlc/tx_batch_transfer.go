@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/NoahFola/simple_bank/apperr"
+)
+
+type BatchTransferItem struct {
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+}
+
+type BatchTransferTxParams struct {
+	FromAccountID int64               `json:"from_account_id"`
+	Items         []BatchTransferItem `json:"items"`
+	// AllOrNothing rolls the whole batch back when any item fails. Otherwise
+	// failed items are reported and the rest go through.
+	AllOrNothing bool `json:"all_or_nothing"`
+}
+
+type BatchTransferItemResult struct {
+	Transfer *Transfer `json:"transfer,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type BatchTransferTxResult struct {
+	FromAccount Account                   `json:"from_account"`
+	Items       []BatchTransferItemResult `json:"items"`
+}
+
+// BatchItemError reports which item of an all-or-nothing batch failed and
+// unwraps to the reason.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// Extensions tells clients which item failed
+func (e *BatchItemError) Extensions() map[string]any {
+	return map[string]any{"index": e.Index}
+}
+
+// BatchTransferTx pays several accounts from one funding account in a
+// single transaction. Every account involved is locked up front in
+// ascending ID order, the same order TransferTx uses, so the funding account
+// is locked once however many items there are. Both accounts of an item
+// must hold the same currency.
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		logger := store.txLogger(ctx)
+		logger.DebugContext(ctx, "batch started", "from_account_id", arg.FromAccountID, "items", len(arg.Items))
+
+		accounts, err := lockBatchAccounts(ctx, q, logger, arg)
+		if err != nil {
+			return err
+		}
+
+		fromAccount := accounts[arg.FromAccountID]
+		var debited int64
+		result.Items = make([]BatchTransferItemResult, len(arg.Items))
+
+		for i, item := range arg.Items {
+			// Items are vetted before anything is written for them, so a
+			// failed item leaves nothing behind to undo.
+			toAccount, err := checkBatchItem(ctx, logger, fromAccount, accounts, debited, item)
+			if err != nil {
+				if arg.AllOrNothing {
+					return &BatchItemError{Index: i, Err: err}
+				}
+				result.Items[i].Error = apperr.Detail(err)
+				continue
+			}
+
+			transfer, err := postBatchItem(ctx, q, logger, fromAccount, toAccount, item)
+			if err != nil {
+				return err
+			}
+			result.Items[i].Transfer = &transfer
+			debited += item.Amount
+		}
+
+		logger.DebugContext(ctx, "updating balance", "account_id", fromAccount.ID,
+			"from", fromAccount.Balance, "to", fromAccount.Balance-debited)
+
+		result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+			ID:      fromAccount.ID,
+			Balance: fromAccount.Balance - debited,
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.DebugContext(ctx, "batch finished", "from_account_id", arg.FromAccountID)
+		return nil
+	})
+
+	return result, err
+}
+
+// lockBatchAccounts locks the funding account and every destination once,
+// in ascending ID order. Destinations that don't exist are left out of the
+// map; the funding account must exist.
+func lockBatchAccounts(ctx context.Context, q *Queries, logger *slog.Logger, arg BatchTransferTxParams) (map[int64]Account, error) {
+	ids := []int64{arg.FromAccountID}
+	seen := map[int64]bool{arg.FromAccountID: true}
+	for _, item := range arg.Items {
+		if !seen[item.ToAccountID] {
+			seen[item.ToAccountID] = true
+			ids = append(ids, item.ToAccountID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		logger.DebugContext(ctx, "locking account", "account_id", id)
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows && id != arg.FromAccountID {
+				continue
+			}
+			return nil, err
+		}
+		accounts[id] = account
+	}
+
+	return accounts, nil
+}
+
+// checkBatchItem vets one item against the accounts locked for the batch,
+// given how much the earlier items already took from the funding account.
+func checkBatchItem(ctx context.Context, logger *slog.Logger, fromAccount Account, accounts map[int64]Account, debited int64, item BatchTransferItem) (Account, error) {
+	if item.ToAccountID == fromAccount.ID {
+		return Account{}, apperr.New(apperr.ValidationFailed,
+			fmt.Sprintf("cannot transfer from account %d to itself", fromAccount.ID))
+	}
+
+	toAccount, ok := accounts[item.ToAccountID]
+	if !ok {
+		return toAccount, fmt.Errorf("account %d: %w", item.ToAccountID, ErrRecordNotFound)
+	}
+
+	if fromAccount.Currency != toAccount.Currency {
+		return toAccount, fmt.Errorf("%w: account %d holds %s, account %d holds %s", ErrCurrencyMismatch,
+			fromAccount.ID, fromAccount.Currency, toAccount.ID, toAccount.Currency)
+	}
+
+	remaining := fromAccount
+	remaining.AvailableBalance -= debited
+	return toAccount, checkFunds(ctx, logger, remaining, item.Amount)
+}
+
+// postBatchItem records one transfer of the batch and credits its
+// destination. The funding account is debited once for the whole batch.
+func postBatchItem(ctx context.Context, q *Queries, logger *slog.Logger, fromAccount, toAccount Account, item BatchTransferItem) (Transfer, error) {
+	transfer, err := q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        item.Amount,
+		ToAmount:      item.Amount,
+		ExchangeRate:  "1",
+		Status:        TransferStatusSettled,
+	})
+	if err != nil {
+		return transfer, err
+	}
+
+	_, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  fromAccount.ID,
+		Amount:     -item.Amount,
+		TransferID: &transfer.ID,
+		EntryType:  EntryTypeTransferDebit,
+	})
+	if err != nil {
+		return transfer, err
+	}
+
+	_, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  toAccount.ID,
+		Amount:     item.Amount,
+		TransferID: &transfer.ID,
+		EntryType:  EntryTypeTransferCredit,
+	})
+	if err != nil {
+		return transfer, err
+	}
+
+	logger.DebugContext(ctx, "crediting account", "account_id", toAccount.ID, "amount", item.Amount)
+	_, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     toAccount.ID,
+		Amount: item.Amount,
+	})
+	return transfer, err
+}
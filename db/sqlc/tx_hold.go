@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/NoahFola/simple_bank/util"
+)
+
+var (
+	// ErrTransferNotAuthorized is returned when capturing or voiding a
+	// transfer that isn't waiting on a hold.
+	ErrTransferNotAuthorized = apperr.New(apperr.Unprocessable, "transfer is not authorized")
+	// ErrHoldExpired is returned when capturing a transfer after its hold
+	// has lapsed.
+	ErrHoldExpired = apperr.New(apperr.Unprocessable, "hold has expired")
+)
+
+// Posted reports whether a transfer in this status has moved money, and so
+// must be backed by a debit and a credit entry.
+func (s TransferStatus) Posted() bool {
+	switch s {
+	case TransferStatusSettled, TransferStatusPartiallyReversed, TransferStatusReversed:
+		return true
+	}
+	return false
+}
+
+type AuthorizeTransferTxParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// Quote converts Amount into the destination account's currency, as
+	// for TransferTx. The rate is fixed at authorization.
+	Quote util.FXQuote `json:"-"`
+	// HoldDuration is how long the funds stay on hold before the
+	// authorization lapses.
+	HoldDuration time.Duration `json:"-"`
+}
+
+// HoldTxResult is the outcome of placing or releasing a hold without moving
+// money: the transfer, its hold and the source account.
+type HoldTxResult struct {
+	Transfer    Transfer `json:"transfer"`
+	Hold        Hold     `json:"hold"`
+	FromAccount Account  `json:"from_account"`
+}
+
+// AuthorizeTransferTx records a transfer without settling it. The amount is
+// put on hold on the source account, reducing its available balance but not
+// its ledger balance, until the transfer is captured, voided or the hold
+// expires.
+func (store *SQLStore) AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (HoldTxResult, error) {
+	var result HoldTxResult
+
+	if err := checkDistinctAccounts(arg.FromAccountID, arg.ToAccountID); err != nil {
+		return result, err
+	}
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		logger := store.txLogger(ctx)
+		logger.DebugContext(ctx, "authorization started",
+			"from_account_id", arg.FromAccountID, "to_account_id", arg.ToAccountID, "amount", arg.Amount)
+
+		transferArg := TransferTxParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			Quote:         arg.Quote,
+		}
+		toAmount, rate, err := transferArg.creditAmount()
+		if err != nil {
+			return err
+		}
+
+		// Only the source balance changes, so the destination is read
+		// without a lock.
+		logger.DebugContext(ctx, "locking account", "account_id", arg.FromAccountID)
+		fromAccount, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
+		if err != nil {
+			return err
+		}
+		toAccount, err := q.GetAccount(ctx, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		if err := transferArg.checkCurrencies(fromAccount, toAccount); err != nil {
+			return err
+		}
+		if err := checkFunds(ctx, logger, fromAccount, arg.Amount); err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  rate,
+			Status:        TransferStatusAuthorized,
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.DebugContext(ctx, "placing hold", "account_id", arg.FromAccountID, "amount", arg.Amount)
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams{
+			AccountID:  arg.FromAccountID,
+			TransferID: result.Transfer.ID,
+			Amount:     arg.Amount,
+			ExpiresAt:  time.Now().Add(arg.HoldDuration),
+		})
+		if err != nil {
+			return err
+		}
+
+		result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     arg.FromAccountID,
+			Amount: arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.DebugContext(ctx, "authorization finished", "transfer_id", result.Transfer.ID)
+		return nil
+	})
+
+	return result, err
+}
+
+// CaptureTransferTx settles an authorized transfer, releasing its hold and
+// posting the debit and credit entries at the amounts fixed when it was
+// authorized.
+func (store *SQLStore) CaptureTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		logger := store.txLogger(ctx)
+		logger.DebugContext(ctx, "capture started", "transfer_id", transferID)
+
+		// The transfer is locked before its accounts, as for reversals
+		transfer, hold, err := lockAuthorizedTransfer(ctx, q, transferID)
+		if err != nil {
+			return err
+		}
+
+		if !time.Now().Before(hold.ExpiresAt) {
+			return fmt.Errorf("%w: hold on transfer %d expired at %s", ErrHoldExpired,
+				transfer.ID, hold.ExpiresAt.Format(time.RFC3339))
+		}
+
+		_, err = q.ReleaseHold(ctx, hold.ID)
+		if err != nil {
+			return err
+		}
+
+		result, err = postTransfer(ctx, q, logger, transfer, hold.Amount, nil)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     transfer.ID,
+			Status: TransferStatusSettled,
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.DebugContext(ctx, "capture finished", "transfer_id", transferID)
+		return nil
+	})
+
+	return result, err
+}
+
+// VoidTransferTx cancels an authorized transfer, marking it failed and
+// giving the held amount back to the available balance. Expired holds are
+// released the same way.
+func (store *SQLStore) VoidTransferTx(ctx context.Context, transferID int64) (HoldTxResult, error) {
+	var result HoldTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		transfer, hold, err := lockAuthorizedTransfer(ctx, q, transferID)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.ReleaseHold(ctx, hold.ID)
+		if err != nil {
+			return err
+		}
+
+		result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     hold.AccountID,
+			Amount: -hold.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     transfer.ID,
+			Status: TransferStatusFailed,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// lockAuthorizedTransfer locks a transfer that is waiting on a hold and
+// returns it with the hold. Holds are only changed while their transfer is
+// locked.
+func lockAuthorizedTransfer(ctx context.Context, q *Queries, transferID int64) (Transfer, Hold, error) {
+	transfer, err := q.GetTransferForUpdate(ctx, transferID)
+	if err != nil {
+		return transfer, Hold{}, err
+	}
+
+	if transfer.Status != TransferStatusAuthorized {
+		return transfer, Hold{}, fmt.Errorf("%w: transfer %d is %s", ErrTransferNotAuthorized,
+			transfer.ID, transfer.Status)
+	}
+
+	hold, err := q.GetHoldByTransfer(ctx, transfer.ID)
+	return transfer, hold, err
+}
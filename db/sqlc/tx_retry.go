@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTxMaxAttempts = 3
+	defaultTxBaseBackoff = 20 * time.Millisecond
+	defaultTxMaxBackoff  = 500 * time.Millisecond
+)
+
+// isRetryableTxError reports whether err means Postgres gave up on the
+// transaction only because of a clash with another one, so running it
+// again from scratch may succeed.
+func isRetryableTxError(err error) bool {
+	switch ErrorCode(err) {
+	case SerializationFailure, DeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// txBackoff returns how long to wait before the given retry, counting from
+// 1. The delay is picked at random up to an exponentially growing ceiling so
+// that transactions that clashed once don't clash again on the retry.
+func txBackoff(retry int, base, max time.Duration) time.Duration {
+	ceiling := max
+	if retry < 32 && base<<(retry-1) < max {
+		ceiling = base << (retry - 1)
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// TxRetryStats counts how transactions retried after serialization
+// failures and deadlocks have fared since the store was created.
+type TxRetryStats struct {
+	// Retries is the number of times a transaction was run again
+	Retries int64 `json:"retries"`
+	// Exhausted is the number of transactions that still failed with a
+	// retryable error after the last attempt
+	Exhausted int64 `json:"exhausted"`
+}
+
+type txRetryCounters struct {
+	retries   atomic.Int64
+	exhausted atomic.Int64
+}
+
+// TxRetryStats returns the retry counts of the store's transactions
+func (store *SQLStore) TxRetryStats() TxRetryStats {
+	return TxRetryStats{
+		Retries:   store.retries.retries.Load(),
+		Exhausted: store.retries.exhausted.Load(),
+	}
+}
+
+// waitTxRetry sleeps before the given retry, returning early with the
+// context's error if it is cancelled first.
+func (store *SQLStore) waitTxRetry(ctx context.Context, retry int) error {
+	timer := time.NewTimer(txBackoff(retry, store.baseBackoff, store.maxBackoff))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryableTxError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"SerializationFailure", &pq.Error{Code: "40001"}, true},
+		{"DeadlockDetected", &pq.Error{Code: "40P01"}, true},
+		{"Wrapped", fmt.Errorf("tx err: %w, rb err: %v", &pq.Error{Code: "40001"}, sql.ErrTxDone), true},
+		{"UniqueViolation", &pq.Error{Code: "23505"}, false},
+		{"NoRows", sql.ErrNoRows, false},
+		{"Nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isRetryableTxError(tt.err))
+		})
+	}
+}
+
+func TestTxBackoff(t *testing.T) {
+	base := 10 * time.Millisecond
+	max := 50 * time.Millisecond
+
+	for retry := 1; retry <= 40; retry++ {
+		ceiling := max
+		if retry <= 3 {
+			ceiling = base << (retry - 1)
+		}
+
+		for i := 0; i < 20; i++ {
+			d := txBackoff(retry, base, max)
+			require.GreaterOrEqual(t, d, time.Duration(0))
+			require.LessOrEqual(t, d, ceiling)
+		}
+	}
+}
+
+func newRetryTestStore(maxAttempts int) *SQLStore {
+	store := NewStore(testDB, testLogger).(*SQLStore)
+	store.maxAttempts = maxAttempts
+	store.baseBackoff = time.Millisecond
+	store.maxBackoff = time.Millisecond
+	return store
+}
+
+func TestExecTxRetriesSerializationFailure(t *testing.T) {
+	store := newRetryTestStore(3)
+	account := createRandomAccount(t)
+
+	calls := 0
+	err := store.execTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable}, func(q *Queries) error {
+		calls++
+		if _, err := q.GetAccountForUpdate(context.Background(), account.ID); err != nil {
+			return err
+		}
+		if calls == 1 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, TxRetryStats{Retries: 1}, store.TxRetryStats())
+}
+
+func TestExecTxGivesUpAfterMaxAttempts(t *testing.T) {
+	store := newRetryTestStore(3)
+
+	calls := 0
+	err := store.execTx(context.Background(), nil, func(q *Queries) error {
+		calls++
+		return &pq.Error{Code: "40P01"}
+	})
+	require.Error(t, err)
+	require.True(t, isRetryableTxError(err))
+	require.Equal(t, 3, calls)
+	require.Equal(t, TxRetryStats{Retries: 2, Exhausted: 1}, store.TxRetryStats())
+}
+
+func TestExecTxDoesNotRetryOtherErrors(t *testing.T) {
+	store := newRetryTestStore(3)
+	errBoom := errors.New("boom")
+
+	calls := 0
+	err := store.execTx(context.Background(), nil, func(q *Queries) error {
+		calls++
+		return errBoom
+	})
+	require.ErrorIs(t, err, errBoom)
+	require.Equal(t, 1, calls)
+	require.Equal(t, TxRetryStats{}, store.TxRetryStats())
+}
+
+func TestExecTxStopsRetryingWhenCancelled(t *testing.T) {
+	store := newRetryTestStore(5)
+	store.baseBackoff = time.Hour
+	store.maxBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		calls++
+		cancel()
+		return &pq.Error{Code: "40001"}
+	})
+	require.True(t, isRetryableTxError(err))
+	require.Equal(t, 1, calls)
+}
+
+func TestExecTxReadOnly(t *testing.T) {
+	store := newRetryTestStore(1)
+	user := createRandomUser(t)
+
+	err := store.execTx(context.Background(), &sql.TxOptions{ReadOnly: true}, func(q *Queries) error {
+		_, err := q.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  0,
+			Currency: "USD",
+		})
+		return err
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/NoahFola/simple_bank/apperr"
+)
+
+var (
+	// ErrTransferNotReversible is returned when reversing a reversal, a
+	// transfer that never settled, or one already refunded in full.
+	ErrTransferNotReversible = apperr.New(apperr.Unprocessable, "transfer cannot be reversed")
+	// ErrInvalidReversalAmount is returned when a refund is larger than what
+	// remains of the original transfer, or too small to convert.
+	ErrInvalidReversalAmount = apperr.New(apperr.Unprocessable, "invalid reversal amount")
+)
+
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount is how much to refund to the original sender, in the currency
+	// the original transfer was sent in. Zero refunds everything not yet
+	// refunded.
+	Amount int64 `json:"amount"`
+}
+
+type ReverseTransferTxResult struct {
+	OriginalTransfer Transfer `json:"original_transfer"`
+	TransferTxResult
+}
+
+// ReverseTransferTx refunds all or part of a transfer by moving money back
+// in a compensating transfer linked to the original. Refunds of the same
+// transfer never add up to more than its amount.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		logger := store.txLogger(ctx)
+		logger.DebugContext(ctx, "reversal started", "transfer_id", arg.TransferID)
+
+		// Locking the original serializes concurrent refunds of it; the
+		// accounts are then locked by moveMoney in the usual order.
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf != nil {
+			return fmt.Errorf("%w: transfer %d is itself a reversal", ErrTransferNotReversible, original.ID)
+		}
+		if original.Status != TransferStatusSettled && original.Status != TransferStatusPartiallyReversed {
+			return fmt.Errorf("%w: transfer %d is %s", ErrTransferNotReversible, original.ID, original.Status)
+		}
+
+		totals, err := q.GetTransferReversedTotals(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining := original.Amount - totals.Refunded
+		refund := arg.Amount
+		if refund == 0 {
+			refund = remaining
+		}
+		if refund <= 0 || refund > remaining {
+			return fmt.Errorf("%w: %d requested, %d remaining", ErrInvalidReversalAmount, refund, remaining)
+		}
+
+		// The recipient gives back the converted amount they received. The
+		// final refund takes exactly what is left so rounding never leaves
+		// a remainder behind.
+		debit := original.ToAmount - totals.Debited
+		if refund < remaining {
+			debit = scaleAmount(refund, original.ToAmount, original.Amount)
+		}
+		if debit <= 0 {
+			return fmt.Errorf("%w: %d is too small to convert", ErrInvalidReversalAmount, refund)
+		}
+
+		result.TransferTxResult, err = moveMoney(ctx, q, logger, moneyMovement{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        debit,
+			ToAmount:      refund,
+			ExchangeRate:  ratioString(refund, debit),
+			ReversalOf:    &original.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		status := TransferStatusPartiallyReversed
+		if refund == remaining {
+			status = TransferStatusReversed
+		}
+
+		result.OriginalTransfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     original.ID,
+			Status: status,
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.DebugContext(ctx, "reversal finished", "transfer_id", arg.TransferID)
+		return nil
+	})
+
+	return result, err
+}
+
+// scaleAmount returns amount * num / den rounded half up, without
+// overflowing on the intermediate product.
+func scaleAmount(amount, num, den int64) int64 {
+	product := new(big.Int).Mul(big.NewInt(amount), big.NewInt(num))
+	product.Mul(product, big.NewInt(2))
+	product.Add(product, big.NewInt(den))
+	return product.Quo(product, big.NewInt(2*den)).Int64()
+}
+
+// ratioString formats num/den as a decimal without trailing zeros
+func ratioString(num, den int64) string {
+	s := big.NewRat(num, den).FloatString(10)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+)
+
+type RunScheduledTransferTxResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// RunScheduledTransferTx claims the most overdue scheduled transfer due at
+// now, skipping any claimed by another worker, and makes its transfer. The
+// claim, the transfer, the run recording the outcome and the move of
+// next_run_at past now all commit together; occurrences missed while
+// nothing was running are not made up. It returns sql.ErrNoRows when
+// nothing is due.
+//
+// A transfer the order itself rules out, for lack of funds, a currency
+// mismatch, a missing account or the same account on both sides, is
+// recorded as a failed run so one bad order cannot hold up the ones behind
+// it. Any other error is returned and leaves the row to be claimed again.
+func (store *SQLStore) RunScheduledTransferTx(ctx context.Context, now time.Time) (RunScheduledTransferTxResult, error) {
+	var result RunScheduledTransferTxResult
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
+		// clear the result of an attempt that was retried
+		result = RunScheduledTransferTxResult{}
+		logger := store.txLogger(ctx)
+
+		scheduled, err := q.ClaimDueScheduledTransfer(ctx, now)
+		if err != nil {
+			return err
+		}
+
+		runArg := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledFor:        scheduled.NextRunAt,
+			Status:              ScheduledRunStatusSucceeded,
+		}
+
+		transfer, err := scheduledTransfer(ctx, q, logger, scheduled)
+		if err != nil {
+			if !scheduledRunFailed(err) {
+				return err
+			}
+			logger.DebugContext(ctx, "scheduled transfer failed", "scheduled_transfer_id", scheduled.ID, "error", err)
+			runArg.Status = ScheduledRunStatusFailed
+			runArg.Error = apperr.Detail(TranslateError(err))
+		} else {
+			runArg.TransferID = &transfer.Transfer.ID
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, runArg)
+		if err != nil {
+			return err
+		}
+
+		next := scheduled.NextRunAfter(now)
+		result.ScheduledTransfer, err = q.AdvanceScheduledTransfer(ctx, AdvanceScheduledTransferParams{
+			ID:        scheduled.ID,
+			NextRunAt: next,
+			Active:    scheduled.EndAt == nil || !next.After(*scheduled.EndAt),
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// scheduledTransfer moves the money of one run. The transfer is vetted
+// before anything is written for it, so a run that fails leaves nothing
+// behind to undo in the claim's transaction.
+func scheduledTransfer(ctx context.Context, q *Queries, logger *slog.Logger, scheduled ScheduledTransfer) (TransferTxResult, error) {
+	arg := TransferTxParams{
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        scheduled.Amount,
+	}
+
+	if err := checkDistinctAccounts(arg.FromAccountID, arg.ToAccountID); err != nil {
+		return TransferTxResult{}, err
+	}
+	fromAccount, toAccount, err := lockAccounts(ctx, q, logger, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+	if err := arg.checkCurrencies(fromAccount, toAccount); err != nil {
+		return TransferTxResult{}, err
+	}
+	if err := checkFunds(ctx, logger, fromAccount, arg.Amount); err != nil {
+		return TransferTxResult{}, err
+	}
+
+	return moveMoney(ctx, q, logger, moneyMovement{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.Amount,
+		ExchangeRate:  "1",
+	})
+}
+
+// scheduledRunFailed reports whether err rules the order out, as opposed to
+// a failure of the database that a later attempt may not run into.
+func scheduledRunFailed(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrCurrencyMismatch) ||
+		errors.Is(err, ErrSameAccount)
+}
+
+// NextRunAfter returns the first occurrence of the schedule after both now
+// and the current next_run_at. Occurrences are counted from start_at, so a
+// monthly order started on the 31st runs on the last day of shorter months
+// and returns to the 31st afterwards.
+func (s ScheduledTransfer) NextRunAfter(now time.Time) time.Time {
+	after := s.NextRunAt
+	if now.After(after) {
+		after = now
+	}
+
+	for k := 1; ; k++ {
+		next := s.occurrence(k)
+		if next.After(after) {
+			return next
+		}
+	}
+}
+
+// occurrence returns the k-th run after start_at
+func (s ScheduledTransfer) occurrence(k int) time.Time {
+	n := k * int(s.ScheduleEvery)
+
+	switch s.ScheduleUnit {
+	case ScheduleUnitWeek:
+		return s.StartAt.AddDate(0, 0, 7*n)
+	case ScheduleUnitMonth:
+		next := s.StartAt.AddDate(0, n, 0)
+		// AddDate overflows into the following month when the day doesn't
+		// exist; clamp to the last day of the intended month instead.
+		if next.Day() != s.StartAt.Day() {
+			next = next.AddDate(0, 0, -next.Day())
+		}
+		return next
+	default:
+		return s.StartAt.AddDate(0, 0, n)
+	}
+}
@@ -2,13 +2,39 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	"github.com/NoahFola/simple_bank/util"
 )
 
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// IdempotencyKey, when set, makes a retry with the same key and payload
+	// return the original result instead of moving the money again.
+	IdempotencyKey string `json:"-"`
+	// IdempotencyKeyOwner is the user the key belongs to. Keys are scoped
+	// per user, so two users may pick the same one.
+	IdempotencyKeyOwner string `json:"-"`
+	// IdempotencyKeyTTL is how long the key is remembered after first use.
+	IdempotencyKeyTTL time.Duration `json:"-"`
+	// Quote converts Amount into the destination account's currency. It may
+	// be left empty when both accounts hold the same currency.
+	Quote util.FXQuote `json:"-"`
+}
+
+// requestHash fingerprints the parts of the transfer an idempotency key is
+// bound to.
+func (arg TransferTxParams) requestHash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("transfer:%d:%d:%d", arg.FromAccountID, arg.ToAccountID, arg.Amount)))
+	return hex.EncodeToString(sum[:])
 }
 
 type TransferTxResult struct {
@@ -19,7 +45,85 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 }
 
-// context key for debugging
+// ErrInsufficientFunds is returned when a transfer would take the source
+// account below its overdraft limit.
+var ErrInsufficientFunds = apperr.New(apperr.InsufficientFunds, "insufficient funds")
+
+// InsufficientFundsError carries the details of a rejected transfer and
+// unwraps to ErrInsufficientFunds.
+type InsufficientFundsError struct {
+	AccountID int64
+	Available int64
+	Requested int64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("account [%d] has insufficient funds: available %d, requested %d",
+		e.AccountID, e.Available, e.Requested)
+}
+
+func (e *InsufficientFundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+// Extensions tells clients how much they could have taken instead
+func (e *InsufficientFundsError) Extensions() map[string]any {
+	return map[string]any{"available_balance": e.Available}
+}
+
+// ErrSameAccount is returned for a transfer from an account to itself,
+// which would otherwise credit the balance read before the debit and
+// create money.
+var ErrSameAccount = apperr.New(apperr.InvalidArgument, "cannot transfer to the same account")
+
+// checkDistinctAccounts rejects a transfer from an account to itself
+func checkDistinctAccounts(fromAccountID, toAccountID int64) error {
+	if fromAccountID == toAccountID {
+		return ErrSameAccount
+	}
+	return nil
+}
+
+// ErrCurrencyMismatch is returned when the transfer's quote does not match
+// the currencies of the two accounts.
+var ErrCurrencyMismatch = apperr.New(apperr.CurrencyMismatch, "currency mismatch")
+
+// creditAmount converts the transfer amount into the destination currency,
+// returning the converted amount and the rate that was applied.
+func (arg TransferTxParams) creditAmount() (int64, string, error) {
+	if arg.Quote.Rate == "" {
+		return arg.Amount, "1", nil
+	}
+
+	toAmount, err := arg.Quote.Convert(arg.Amount)
+	if err != nil {
+		return 0, "", err
+	}
+	if toAmount <= 0 {
+		return 0, "", apperr.New(apperr.Unprocessable,
+			fmt.Sprintf("amount %d converts to %d %s", arg.Amount, toAmount, arg.Quote.To))
+	}
+	return toAmount, arg.Quote.Rate, nil
+}
+
+// checkCurrencies verifies the quote covers the currencies of both accounts.
+func (arg TransferTxParams) checkCurrencies(fromAccount, toAccount Account) error {
+	if arg.Quote.Rate == "" {
+		if fromAccount.Currency != toAccount.Currency {
+			return fmt.Errorf("%w: no quote for %s to %s", ErrCurrencyMismatch,
+				fromAccount.Currency, toAccount.Currency)
+		}
+		return nil
+	}
+
+	if arg.Quote.From != fromAccount.Currency || arg.Quote.To != toAccount.Currency {
+		return fmt.Errorf("%w: quote for %s to %s, accounts hold %s and %s", ErrCurrencyMismatch,
+			arg.Quote.From, arg.Quote.To, fromAccount.Currency, toAccount.Currency)
+	}
+	return nil
+}
+
+// context key naming a transaction in debug logs
 type txKeyType string
 
 var txKey = txKeyType("txName")
@@ -28,94 +132,228 @@ var txKey = txKeyType("txName")
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	if err := checkDistinctAccounts(arg.FromAccountID, arg.ToAccountID); err != nil {
+		return result, err
+	}
+
+	err := store.execTx(ctx, nil, func(q *Queries) error {
 		var err error
 
-		// Extract transaction name for debugging
-		txName, _ := ctx.Value(txKey).(string)
-		fmt.Printf("[%s] >> START transaction\n", txName)
+		logger := store.txLogger(ctx)
+		logger.DebugContext(ctx, "transfer started",
+			"from_account_id", arg.FromAccountID, "to_account_id", arg.ToAccountID, "amount", arg.Amount)
 
-		// Create transfer record
-		fmt.Printf("[%s] Creating transfer record\n", txName)
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
-		if err != nil {
-			return err
+		// Replay the original result if this key was already used
+		if arg.IdempotencyKey != "" {
+			logger.DebugContext(ctx, "claiming idempotency key", "idempotency_key", arg.IdempotencyKey)
+			replay, err := reserveIdempotencyKey(ctx, q, arg.IdempotencyKeyOwner, arg.IdempotencyKey,
+				arg.requestHash(), arg.IdempotencyKeyTTL)
+			if err != nil {
+				return err
+			}
+			if replay != nil {
+				logger.DebugContext(ctx, "transfer replayed", "idempotency_key", arg.IdempotencyKey)
+				return json.Unmarshal(replay, &result)
+			}
 		}
 
-		// Create debit entry
-		fmt.Printf("[%s] Creating debit entry for account %d\n", txName, arg.FromAccountID)
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
+		toAmount, rate, err := arg.creditAmount()
 		if err != nil {
 			return err
 		}
 
-		// Create credit entry
-		fmt.Printf("[%s] Creating credit entry for account %d\n", txName, arg.ToAccountID)
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		result, err = moveMoney(ctx, q, logger, moneyMovement{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  rate,
+			// Entries are written in each account's own currency, so the
+			// quote must agree with what the accounts actually hold.
+			checkAccounts: arg.checkCurrencies,
 		})
 		if err != nil {
 			return err
 		}
 
-		// Lock accounts in consistent order to avoid deadlock
-		var fromAccount, toAccount Account
-		if arg.FromAccountID < arg.ToAccountID {
-			fmt.Printf("[%s] Locking fromAccount %d\n", txName, arg.FromAccountID)
-			fromAccount, err = q.GetAccountForUpdate(ctx, arg.FromAccountID)
+		if arg.IdempotencyKey != "" {
+			err = saveIdempotencyResponse(ctx, q, arg.IdempotencyKeyOwner, arg.IdempotencyKey, result)
 			if err != nil {
 				return err
 			}
+		}
 
-			fmt.Printf("[%s] Locking toAccount %d\n", txName, arg.ToAccountID)
-			toAccount, err = q.GetAccountForUpdate(ctx, arg.ToAccountID)
-			if err != nil {
-				return err
-			}
-		} else {
-			fmt.Printf("[%s] Locking toAccount %d\n", txName, arg.ToAccountID)
-			toAccount, err = q.GetAccountForUpdate(ctx, arg.ToAccountID)
-			if err != nil {
-				return err
-			}
+		logger.DebugContext(ctx, "transfer finished", "transfer_id", result.Transfer.ID)
+		return nil
+	})
 
-			fmt.Printf("[%s] Locking fromAccount %d\n", txName, arg.FromAccountID)
-			fromAccount, err = q.GetAccountForUpdate(ctx, arg.FromAccountID)
-			if err != nil {
-				return err
-			}
-		}
+	return result, err
+}
 
-		// Update balances
-		fmt.Printf("[%s] Updating balance of fromAccount %d: %d -> %d\n", txName,
-			fromAccount.ID, fromAccount.Balance, fromAccount.Balance-arg.Amount)
+// moneyMovement is a transfer row together with the entries and balance
+// changes behind it.
+type moneyMovement struct {
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64
+	ToAmount      int64
+	ExchangeRate  string
+	ReversalOf    *int64
+	// checkAccounts, when set, vets the locked accounts before any balance
+	// is changed.
+	checkAccounts func(fromAccount, toAccount Account) error
+}
 
-		result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
-			ID:      fromAccount.ID,
-			Balance: fromAccount.Balance - arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+// moveMoney records a transfer with its debit and credit entries and updates
+// both balances. It must run inside a transaction; returning an error rolls
+// back everything it wrote.
+func moveMoney(ctx context.Context, q *Queries, logger *slog.Logger, m moneyMovement) (TransferTxResult, error) {
+	// Create transfer record
+	logger.DebugContext(ctx, "creating transfer record")
+	transfer, err := q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: m.FromAccountID,
+		ToAccountID:   m.ToAccountID,
+		Amount:        m.Amount,
+		ToAmount:      m.ToAmount,
+		ExchangeRate:  m.ExchangeRate,
+		ReversalOf:    m.ReversalOf,
+		Status:        TransferStatusSettled,
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	return postTransfer(ctx, q, logger, transfer, 0, m.checkAccounts)
+}
+
+// postTransfer writes the debit and credit entries of an existing transfer
+// and applies them to both balances. held is first released from the source
+// account's held balance, so money put on hold for this transfer counts as
+// available to it.
+func postTransfer(ctx context.Context, q *Queries, logger *slog.Logger, transfer Transfer, held int64,
+	checkAccounts func(fromAccount, toAccount Account) error) (TransferTxResult, error) {
+	result := TransferTxResult{Transfer: transfer}
+
+	err := checkDistinctAccounts(transfer.FromAccountID, transfer.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	// Create debit entry
+	logger.DebugContext(ctx, "creating debit entry", "account_id", transfer.FromAccountID)
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  transfer.FromAccountID,
+		Amount:     -transfer.Amount,
+		TransferID: &transfer.ID,
+		EntryType:  EntryTypeTransferDebit,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Create credit entry
+	logger.DebugContext(ctx, "creating credit entry", "account_id", transfer.ToAccountID)
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  transfer.ToAccountID,
+		Amount:     transfer.ToAmount,
+		TransferID: &transfer.ID,
+		EntryType:  EntryTypeTransferCredit,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	fromAccount, toAccount, err := lockAccounts(ctx, q, logger, transfer.FromAccountID, transfer.ToAccountID)
+	if err != nil {
+		return result, err
+	}
 
-		fmt.Printf("[%s] Updating balance of toAccount %d: %d -> %d\n", txName,
-			toAccount.ID, toAccount.Balance, toAccount.Balance+arg.Amount)
+	if checkAccounts != nil {
+		if err := checkAccounts(fromAccount, toAccount); err != nil {
+			return result, err
+		}
+	}
 
-		result.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
-			ID:      toAccount.ID,
-			Balance: toAccount.Balance + arg.Amount,
+	if held > 0 {
+		logger.DebugContext(ctx, "releasing hold", "account_id", fromAccount.ID, "amount", held)
+		fromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     fromAccount.ID,
+			Amount: -held,
 		})
 		if err != nil {
-			return err
+			return result, err
 		}
+	}
 
-		fmt.Printf("[%s] >> END transaction\n", txName)
-		return nil
+	// Reject the transfer if it would overdraw the source account
+	if err := checkFunds(ctx, logger, fromAccount, transfer.Amount); err != nil {
+		return result, err
+	}
+
+	// Update balances
+	logger.DebugContext(ctx, "updating balance", "account_id", fromAccount.ID,
+		"from", fromAccount.Balance, "to", fromAccount.Balance-transfer.Amount)
+
+	result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+		ID:      fromAccount.ID,
+		Balance: fromAccount.Balance - transfer.Amount,
 	})
+	if err != nil {
+		return result, err
+	}
+
+	logger.DebugContext(ctx, "updating balance", "account_id", toAccount.ID,
+		"from", toAccount.Balance, "to", toAccount.Balance+transfer.ToAmount)
 
+	result.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+		ID:      toAccount.ID,
+		Balance: toAccount.Balance + transfer.ToAmount,
+	})
 	return result, err
 }
+
+// lockAccounts locks both accounts of a transfer in ascending ID order so
+// that concurrent transfers between the same accounts cannot deadlock.
+func lockAccounts(ctx context.Context, q *Queries, logger *slog.Logger, fromAccountID, toAccountID int64) (Account, Account, error) {
+	var fromAccount, toAccount Account
+	var err error
+
+	if fromAccountID < toAccountID {
+		logger.DebugContext(ctx, "locking account", "account_id", fromAccountID)
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return fromAccount, toAccount, err
+		}
+
+		logger.DebugContext(ctx, "locking account", "account_id", toAccountID)
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return fromAccount, toAccount, err
+	}
+
+	logger.DebugContext(ctx, "locking account", "account_id", toAccountID)
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return fromAccount, toAccount, err
+	}
+
+	logger.DebugContext(ctx, "locking account", "account_id", fromAccountID)
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return fromAccount, toAccount, err
+}
+
+// checkFunds rejects taking amount out of a locked account when its
+// available balance, which excludes money on hold, and its overdraft limit
+// don't cover it.
+func checkFunds(ctx context.Context, logger *slog.Logger, account Account, amount int64) error {
+	available := account.AvailableBalance + account.OverdraftLimit
+	if available < amount {
+		logger.DebugContext(ctx, "insufficient funds", "account_id", account.ID,
+			"available", available, "requested", amount)
+		return &InsufficientFundsError{
+			AccountID: account.ID,
+			Available: available,
+			Requested: amount,
+		}
+	}
+	return nil
+}
@@ -1,13 +1,30 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/NoahFola/simple_bank/api"
+	"github.com/NoahFola/simple_bank/db/migration"
 	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/metrics"
+	"github.com/NoahFola/simple_bank/reconcile"
+	"github.com/NoahFola/simple_bank/tracing"
 	"github.com/NoahFola/simple_bank/util"
+	"github.com/NoahFola/simple_bank/worker"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
@@ -15,17 +32,236 @@ func main() {
 	if err != nil {
 		log.Fatal("Cannot load config: ", err)
 	}
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+
+	logger, err := util.NewLogger(os.Stderr, config.LogLevel, config.LogFormat)
+	if err != nil {
+		log.Fatal("cannot create logger: ", err)
+	}
+	// Route the standard logger, used by the workers, through the same handler
+	slog.SetDefault(logger)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(config, logger, os.Args[2:])
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := db.Open(ctx, config, logger)
 	if err != nil {
 		log.Fatal("Cannot connect to the database", err)
 	}
 
-	store := db.NewStore(conn)
-	server := api.NewServer(store)
+	if config.MigrateOnStart {
+		if err := migrateUp(config, logger); err != nil {
+			log.Fatal("cannot migrate database ", err)
+		}
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(ctx, db.NewStore(conn, logger), os.Args[2:])
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(conn, metrics.Namespace),
+	)
+
+	shutdownTracing, err := tracing.Setup(ctx, config)
+	if err != nil {
+		log.Fatal("cannot set up tracing ", err)
+	}
+
+	store, err := metrics.NewStore(tracing.NewStore(db.NewStore(conn, logger), nil), registry)
+	if err != nil {
+		log.Fatal("cannot register store metrics ", err)
+	}
 
-	err = server.Start(config.ServerAddress)
+	schemaVersion, err := migration.LatestVersion()
+	if err != nil {
+		log.Fatal("cannot read schema version ", err)
+	}
+	config.SchemaVersion = int64(schemaVersion)
 
+	server, err := api.NewServer(config, store, logger, registry)
 	if err != nil {
+		log.Fatal("cannot create server ", err)
+	}
+
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		worker.NewHoldExpirer(store, config.HoldExpiryInterval, logger).Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		worker.NewScheduler(store, config.SchedulerInterval, nil, logger).Run(ctx)
+	}()
+
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- server.Start(config.ServerAddress)
+	}()
+	logger.Info("server started", "address", config.ServerAddress)
+
+	// Metrics are only served when an address is configured for them, apart
+	// from the public API.
+	var metricsServer *metrics.Server
+	if config.MetricsAddress != "" {
+		metricsServer = metrics.NewServer(registry, logger)
+		go func() {
+			serverErr <- metricsServer.Start(config.MetricsAddress)
+		}()
+		logger.Info("metrics server started", "address", config.MetricsAddress)
+	}
+
+	select {
+	case err := <-serverErr:
 		log.Fatal("cannot start server ", err)
+	case <-ctx.Done():
+	}
+	stop()
+	logger.Info("shutting down")
+
+	// Stop accepting requests and let in-flight ones, such as a transfer
+	// half way through its transaction, finish before the pool is closed.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("cannot shut down server gracefully", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("cannot shut down metrics server gracefully", "error", err)
+		}
+	}
+	workers.Wait()
+
+	if err := conn.Close(); err != nil {
+		logger.Error("cannot close database", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("cannot flush traces", "error", err)
+	}
+	logger.Info("server stopped")
+}
+
+// runMigrate applies or rolls back the embedded migrations:
+//
+//	migrate up [N]      apply all pending migrations, or the next N
+//	migrate down [N]    roll back the last N migrations, 1 by default
+//	migrate version     print the migration the database is at
+//	migrate force V     mark migration V as applied after a failed one was fixed by hand
+func runMigrate(config util.Config, logger *slog.Logger, args []string) {
+	if len(args) == 0 {
+		log.Fatal("usage: migrate up [N] | down [N] | version | force V")
+	}
+
+	migrator, err := migration.New(config.DBSource, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer migrator.Close()
+
+	steps := func(def int) int {
+		if len(args) < 2 {
+			return def
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid number of migrations %q", args[1])
+		}
+		return n
+	}
+
+	switch args[0] {
+	case "up":
+		if n := steps(0); n > 0 {
+			err = migrator.Steps(n)
+		} else {
+			err = migrator.Up()
+		}
+	case "down":
+		err = migrator.Steps(-steps(1))
+	case "version":
+		var version uint
+		var dirty bool
+		version, dirty, err = migrator.Version()
+		if err == nil {
+			fmt.Printf("version %d, dirty %t\n", version, dirty)
+		}
+	case "force":
+		if len(args) < 2 {
+			log.Fatal("usage: migrate force V")
+		}
+		var version int
+		version, err = strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalf("invalid version %q", args[1])
+		}
+		err = migrator.Force(version)
+	default:
+		log.Fatalf("unknown migrate command %q", args[0])
+	}
+
+	if err != nil {
+		log.Fatal("cannot migrate database ", err)
+	}
+}
+
+// migrateUp applies all pending migrations before the server starts
+func migrateUp(config util.Config, logger *slog.Logger) error {
+	migrator, err := migration.New(config.DBSource, logger)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
+
+// runReconcile checks the ledger and prints the report as JSON on stdout.
+// It exits with status 2 when any discrepancy was found, and stops early
+// when ctx is cancelled.
+func runReconcile(ctx context.Context, store db.Store, args []string) {
+	var accountIDs []int64
+
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "write a corrective entry for every account whose entries don't sum to its balance")
+	batchSize := flags.Int("batch-size", 100, "number of rows read per query")
+	flags.Func("account-ids", "comma-separated IDs of the only accounts to check", func(value string) error {
+		for _, field := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid account ID %q", field)
+			}
+			accountIDs = append(accountIDs, id)
+		}
+		return nil
+	})
+	flags.Parse(args)
+
+	report, err := reconcile.New(store).Run(ctx, reconcile.Options{
+		BatchSize:  int32(*batchSize),
+		Fix:        *fix,
+		AccountIDs: accountIDs,
+	})
+	if err != nil {
+		log.Fatal("cannot reconcile ledger ", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("cannot write report ", err)
+	}
+
+	if !report.OK() {
+		os.Exit(2)
 	}
 }
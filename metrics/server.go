@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// readHeaderTimeout bounds how long a scrape may take to send its headers
+const readHeaderTimeout = 5 * time.Second
+
+// Server serves /metrics on a listener of its own, kept apart from the API
+// so that the metrics are only reachable where the operator exposes them.
+type Server struct {
+	httpServer *http.Server
+}
+
+// NewServer creates a server for the metrics gathered by gatherer. Errors
+// of the HTTP server are logged to logger; a nil logger discards them.
+func NewServer(gatherer prometheus.Gatherer, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+
+	return &Server{
+		httpServer: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		},
+	}
+}
+
+// Start listens on address and serves metrics until Shutdown is called. It
+// returns nil once the server was shut down.
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves metrics on listener until Shutdown is called
+func (s *Server) Serve(listener net.Listener) error {
+	err := s.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting scrapes and waits for the ones in flight, or for
+// ctx to be done, whichever comes first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "test_total",
+		Help:      "Counter for the test.",
+	})
+	require.NoError(t, registry.Register(counter))
+	counter.Add(3)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := NewServer(registry, nil)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	rsp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Contains(t, string(body), "simple_bank_test_total 3")
+
+	// only the metrics are served here
+	rsp, err = http.Get("http://" + listener.Addr().String() + "/healthz")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	require.NoError(t, <-served)
+}
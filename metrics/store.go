@@ -0,0 +1,225 @@
+// Package metrics exports Prometheus metrics about the bank's activity.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/NoahFola/simple_bank/apperr"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes the names of all the bank's metrics
+const Namespace = "simple_bank"
+
+// Kinds of transfer counted by simple_bank_transfers_created_total
+const (
+	TransferKindTransfer      = "transfer"
+	TransferKindBatch         = "batch"
+	TransferKindReversal      = "reversal"
+	TransferKindAuthorization = "authorization"
+	TransferKindScheduled     = "scheduled"
+)
+
+// causeCanceled is the rollback cause of transactions abandoned because
+// their request was cancelled or timed out.
+const causeCanceled = "canceled"
+
+// Store is a db.Store that counts the transfers made through it, the money
+// they move and the transactions that are rolled back. A transfer replayed
+// from its idempotency key moves no money and is not counted again.
+type Store struct {
+	db.Store
+
+	transfersCreated *prometheus.CounterVec
+	amountMoved      *prometheus.CounterVec
+	rollbacks        *prometheus.CounterVec
+}
+
+// NewStore wraps store and registers its metrics with registerer
+func NewStore(store db.Store, registerer prometheus.Registerer) (*Store, error) {
+	s := &Store{
+		Store: store,
+		transfersCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "transfers_created_total",
+			Help:      "Number of transfers created, by kind.",
+		}, []string{"kind"}),
+		amountMoved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "transfer_amount_total",
+			Help:      "Amount settled by transfers, in minor units of the source account currency.",
+		}, []string{"currency"}),
+		rollbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "tx_rollbacks_total",
+			Help:      "Number of store transactions rolled back, by transaction and error code.",
+		}, []string{"tx", "cause"}),
+	}
+
+	collectors := []prometheus.Collector{
+		s.transfersCreated,
+		s.amountMoved,
+		s.rollbacks,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "tx_retries_total",
+			Help:      "Number of times a transaction was run again after a serialization failure or deadlock.",
+		}, func() float64 { return float64(store.TxRetryStats().Retries) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "tx_retries_exhausted_total",
+			Help:      "Number of transactions that still failed with a retryable error after the last attempt.",
+		}, func() float64 { return float64(store.TxRetryStats().Exhausted) }),
+	}
+	for _, c := range collectors {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// observeTx counts a rollback of the named transaction when err is set
+func (s *Store) observeTx(tx string, err error) {
+	if err == nil {
+		return
+	}
+
+	cause := string(apperr.CodeOf(err))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		cause = causeCanceled
+	}
+	s.rollbacks.WithLabelValues(tx, cause).Inc()
+}
+
+// observeSettled adds a settled transfer to the amount moved
+func (s *Store) observeSettled(result db.TransferTxResult) {
+	s.amountMoved.WithLabelValues(result.FromAccount.Currency).Add(float64(result.Transfer.Amount))
+}
+
+// TransferTx counts the transfer unless its idempotency key already held a
+// result, in which case the call replays it.
+func (s *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	replay := s.keyInUse(ctx, arg)
+	result, err := s.Store.TransferTx(ctx, arg)
+	s.observeTx("TransferTx", err)
+	if err == nil && !replay {
+		s.transfersCreated.WithLabelValues(TransferKindTransfer).Inc()
+		s.observeSettled(result)
+	}
+	return result, err
+}
+
+// keyInUse reports whether the idempotency key of arg is already stored and
+// live. Two requests racing with a new key may both find it unused.
+func (s *Store) keyInUse(ctx context.Context, arg db.TransferTxParams) bool {
+	if arg.IdempotencyKey == "" {
+		return false
+	}
+
+	key, err := s.Store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: arg.IdempotencyKeyOwner,
+		Key:      arg.IdempotencyKey,
+	})
+	return err == nil && key.ExpiresAt.After(time.Now())
+}
+
+func (s *Store) BatchTransferTx(ctx context.Context, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+	result, err := s.Store.BatchTransferTx(ctx, arg)
+	s.observeTx("BatchTransferTx", err)
+	if err == nil {
+		for _, item := range result.Items {
+			if item.Transfer == nil {
+				continue
+			}
+			s.transfersCreated.WithLabelValues(TransferKindBatch).Inc()
+			s.amountMoved.WithLabelValues(result.FromAccount.Currency).Add(float64(item.Transfer.Amount))
+		}
+	}
+	return result, err
+}
+
+func (s *Store) ReverseTransferTx(ctx context.Context, arg db.ReverseTransferTxParams) (db.ReverseTransferTxResult, error) {
+	result, err := s.Store.ReverseTransferTx(ctx, arg)
+	s.observeTx("ReverseTransferTx", err)
+	if err == nil {
+		s.transfersCreated.WithLabelValues(TransferKindReversal).Inc()
+		s.observeSettled(result.TransferTxResult)
+	}
+	return result, err
+}
+
+func (s *Store) AuthorizeTransferTx(ctx context.Context, arg db.AuthorizeTransferTxParams) (db.HoldTxResult, error) {
+	result, err := s.Store.AuthorizeTransferTx(ctx, arg)
+	s.observeTx("AuthorizeTransferTx", err)
+	if err == nil {
+		s.transfersCreated.WithLabelValues(TransferKindAuthorization).Inc()
+	}
+	return result, err
+}
+
+// CaptureTransferTx settles an authorized transfer, which was counted as
+// created when it was authorized.
+func (s *Store) CaptureTransferTx(ctx context.Context, transferID int64) (db.TransferTxResult, error) {
+	result, err := s.Store.CaptureTransferTx(ctx, transferID)
+	s.observeTx("CaptureTransferTx", err)
+	if err == nil {
+		s.observeSettled(result)
+	}
+	return result, err
+}
+
+func (s *Store) VoidTransferTx(ctx context.Context, transferID int64) (db.HoldTxResult, error) {
+	result, err := s.Store.VoidTransferTx(ctx, transferID)
+	s.observeTx("VoidTransferTx", err)
+	return result, err
+}
+
+func (s *Store) DepositTx(ctx context.Context, arg db.DepositTxParams) (db.AccountTxResult, error) {
+	result, err := s.Store.DepositTx(ctx, arg)
+	s.observeTx("DepositTx", err)
+	return result, err
+}
+
+func (s *Store) WithdrawTx(ctx context.Context, arg db.WithdrawTxParams) (db.AccountTxResult, error) {
+	result, err := s.Store.WithdrawTx(ctx, arg)
+	s.observeTx("WithdrawTx", err)
+	return result, err
+}
+
+func (s *Store) AdjustBalanceTx(ctx context.Context, arg db.AdjustBalanceTxParams) (db.AccountTxResult, error) {
+	result, err := s.Store.AdjustBalanceTx(ctx, arg)
+	s.observeTx("AdjustBalanceTx", err)
+	return result, err
+}
+
+func (s *Store) ReconcileAccountTx(ctx context.Context, accountID int64) (db.AccountTxResult, error) {
+	result, err := s.Store.ReconcileAccountTx(ctx, accountID)
+	s.observeTx("ReconcileAccountTx", err)
+	return result, err
+}
+
+// RunScheduledTransferTx counts the transfer a run made. Finding nothing
+// due is not counted as a rollback, since the scheduler polls for it.
+func (s *Store) RunScheduledTransferTx(ctx context.Context, now time.Time) (db.RunScheduledTransferTxResult, error) {
+	result, err := s.Store.RunScheduledTransferTx(ctx, now)
+	if !errors.Is(err, sql.ErrNoRows) {
+		s.observeTx("RunScheduledTransferTx", err)
+	}
+	if err == nil && result.Run.TransferID != nil {
+		s.transfersCreated.WithLabelValues(TransferKindScheduled).Inc()
+
+		// The run only records the transfer, so the currency it was made
+		// in is looked up on the source account.
+		from, err := s.Store.GetAccount(ctx, result.ScheduledTransfer.FromAccountID)
+		if err == nil {
+			s.amountMoved.WithLabelValues(from.Currency).Add(float64(result.ScheduledTransfer.Amount))
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, *mockdb.MockStore) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	mock := mockdb.NewMockStore(ctrl)
+
+	store, err := NewStore(mock, prometheus.NewRegistry())
+	require.NoError(t, err)
+	return store, mock
+}
+
+func transferResult(currency string, amount int64) db.TransferTxResult {
+	return db.TransferTxResult{
+		Transfer:    db.Transfer{ID: 1, Amount: amount},
+		FromAccount: db.Account{ID: 1, Currency: currency},
+	}
+}
+
+func TestStoreCountsTransfers(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	mock.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(transferResult("USD", 100), nil)
+	mock.EXPECT().CaptureTransferTx(gomock.Any(), gomock.Any()).Return(transferResult("USD", 50), nil)
+	mock.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Return(db.BatchTransferTxResult{
+		FromAccount: db.Account{Currency: "EUR"},
+		Items: []db.BatchTransferItemResult{
+			{Transfer: &db.Transfer{Amount: 10}},
+			{Error: "insufficient funds"},
+			{Transfer: &db.Transfer{Amount: 20}},
+		},
+	}, nil)
+	transferID := int64(2)
+	mock.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+		Return(db.RunScheduledTransferTxResult{
+			ScheduledTransfer: db.ScheduledTransfer{FromAccountID: 3, Amount: 5},
+			Run:               db.ScheduledTransferRun{TransferID: &transferID},
+		}, nil)
+	mock.EXPECT().GetAccount(gomock.Any(), int64(3)).Return(db.Account{ID: 3, Currency: "EUR"}, nil)
+
+	_, err := store.TransferTx(ctx, db.TransferTxParams{})
+	require.NoError(t, err)
+	_, err = store.CaptureTransferTx(ctx, 1)
+	require.NoError(t, err)
+	_, err = store.BatchTransferTx(ctx, db.BatchTransferTxParams{})
+	require.NoError(t, err)
+	_, err = store.RunScheduledTransferTx(ctx, time.Now())
+	require.NoError(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(store.transfersCreated.WithLabelValues(TransferKindTransfer)))
+	require.Equal(t, 2.0, testutil.ToFloat64(store.transfersCreated.WithLabelValues(TransferKindBatch)))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.transfersCreated.WithLabelValues(TransferKindScheduled)))
+	require.Equal(t, 150.0, testutil.ToFloat64(store.amountMoved.WithLabelValues("USD")))
+	require.Equal(t, 35.0, testutil.ToFloat64(store.amountMoved.WithLabelValues("EUR")))
+	require.Zero(t, testutil.CollectAndCount(store.rollbacks))
+}
+
+func TestStoreSkipsReplays(t *testing.T) {
+	store, mock := newTestStore(t)
+
+	ctx := context.Background()
+
+	arg := db.TransferTxParams{IdempotencyKey: "retry", IdempotencyKeyOwner: "alice"}
+	keyArg := db.GetIdempotencyKeyParams{Username: "alice", Key: "retry"}
+	gomock.InOrder(
+		mock.EXPECT().GetIdempotencyKey(gomock.Any(), keyArg).Return(db.IdempotencyKey{}, db.ErrRecordNotFound),
+		mock.EXPECT().TransferTx(gomock.Any(), arg).Return(transferResult("USD", 100), nil),
+		mock.EXPECT().GetIdempotencyKey(gomock.Any(), keyArg).
+			Return(db.IdempotencyKey{ExpiresAt: time.Now().Add(time.Hour)}, nil),
+		mock.EXPECT().TransferTx(gomock.Any(), arg).Return(transferResult("USD", 100), nil),
+	)
+
+	_, err := store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+	_, err = store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(store.transfersCreated.WithLabelValues(TransferKindTransfer)))
+	require.Equal(t, 100.0, testutil.ToFloat64(store.amountMoved.WithLabelValues("USD")))
+}
+
+func TestStoreCountsRollbacks(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	mock.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+		Return(db.TransferTxResult{}, &db.InsufficientFundsError{AccountID: 1})
+	mock.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+		Return(db.TransferTxResult{}, fmt.Errorf("transfer: %w", context.Canceled))
+	mock.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).
+		Return(db.AccountTxResult{}, db.ErrRecordNotFound)
+	mock.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+		Return(db.RunScheduledTransferTxResult{}, db.TranslateError(sql.ErrNoRows))
+
+	_, err := store.TransferTx(ctx, db.TransferTxParams{})
+	require.Error(t, err)
+	_, err = store.TransferTx(ctx, db.TransferTxParams{})
+	require.Error(t, err)
+	_, err = store.WithdrawTx(ctx, db.WithdrawTxParams{})
+	require.Error(t, err)
+	_, err = store.RunScheduledTransferTx(ctx, time.Now())
+	require.Error(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(store.rollbacks.WithLabelValues("TransferTx", "insufficient_funds")))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.rollbacks.WithLabelValues("TransferTx", causeCanceled)))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.rollbacks.WithLabelValues("WithdrawTx", "not_found")))
+	require.Equal(t, 3, testutil.CollectAndCount(store.rollbacks))
+	require.Zero(t, testutil.CollectAndCount(store.transfersCreated))
+}
+
+func TestStoreExportsRetryStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock := mockdb.NewMockStore(ctrl)
+	mock.EXPECT().TxRetryStats().AnyTimes().Return(db.TxRetryStats{Retries: 4, Exhausted: 1})
+
+	registry := prometheus.NewRegistry()
+	_, err := NewStore(mock, registry)
+	require.NoError(t, err)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		values[family.GetName()] = family.GetMetric()[0].GetCounter().GetValue()
+	}
+	require.Equal(t, 4.0, values["simple_bank_tx_retries_total"])
+	require.Equal(t, 1.0, values["simple_bank_tx_retries_exhausted_total"])
+}
@@ -0,0 +1,30 @@
+package reconcile
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/util"
+	_ "github.com/lib/pq"
+)
+
+var testQueries *db.Queries
+var testDB *sql.DB
+
+func TestMain(m *testing.M) {
+	config, err := util.LoadConfig("..")
+	if err != nil {
+		log.Fatal("Cannot load config: ", err)
+	}
+	testDB, err = sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		log.Fatal("Cannot connect to the database", err)
+	}
+
+	testQueries = db.New(testDB)
+
+	os.Exit(m.Run())
+}
@@ -0,0 +1,238 @@
+// Package reconcile verifies the ledger: every account balance must equal
+// the sum of its entries, every posted transfer must have exactly one debit
+// and one credit entry while unsettled ones have none, and only transfer legs
+// may link to a transfer.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+)
+
+const defaultBatchSize = 100
+
+// AccountDiscrepancy is an account whose balance doesn't match its entries
+type AccountDiscrepancy struct {
+	AccountID       int64 `json:"account_id"`
+	StoredBalance   int64 `json:"stored_balance"`
+	ComputedBalance int64 `json:"computed_balance"`
+	// CorrectionEntryID is set when a corrective entry was written
+	CorrectionEntryID int64 `json:"correction_entry_id,omitempty"`
+}
+
+// TransferDiscrepancy is a posted transfer without exactly one debit and
+// one credit entry, or an unsettled one with any entry.
+type TransferDiscrepancy struct {
+	TransferID    int64             `json:"transfer_id"`
+	FromAccountID int64             `json:"from_account_id"`
+	ToAccountID   int64             `json:"to_account_id"`
+	Status        db.TransferStatus `json:"status"`
+	DebitEntries  int64             `json:"debit_entries"`
+	CreditEntries int64             `json:"credit_entries"`
+}
+
+// OrphanEntry is a transfer leg without a transfer, or any other entry
+// that links to one.
+type OrphanEntry struct {
+	EntryID    int64        `json:"entry_id"`
+	AccountID  int64        `json:"account_id"`
+	Amount     int64        `json:"amount"`
+	EntryType  db.EntryType `json:"entry_type"`
+	TransferID *int64       `json:"transfer_id"`
+}
+
+// Report is the outcome of a reconciliation run
+type Report struct {
+	AccountsScanned  int                   `json:"accounts_scanned"`
+	TransfersScanned int                   `json:"transfers_scanned"`
+	Accounts         []AccountDiscrepancy  `json:"account_discrepancies"`
+	Transfers        []TransferDiscrepancy `json:"transfer_discrepancies"`
+	OrphanEntries    []OrphanEntry         `json:"orphan_entries"`
+}
+
+// OK reports whether no discrepancy was found
+func (r Report) OK() bool {
+	return len(r.Accounts) == 0 && len(r.Transfers) == 0 && len(r.OrphanEntries) == 0
+}
+
+// Options controls a reconciliation run
+type Options struct {
+	// BatchSize is how many rows are read per query; defaults to 100.
+	BatchSize int32
+	// Fix writes a corrective entry for every account whose entries don't
+	// sum to its balance. Transfer and entry discrepancies are only reported.
+	Fix bool
+	// AccountIDs, when set, limits the run to these accounts: only they are
+	// reported or fixed, along with the transfers and entries touching them.
+	AccountIDs []int64
+}
+
+// covers reports whether any of the accounts is within the run's scope
+func (opts Options) covers(accountIDs ...int64) bool {
+	if len(opts.AccountIDs) == 0 {
+		return true
+	}
+	for _, id := range accountIDs {
+		if slices.Contains(opts.AccountIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reconciler scans the ledger for discrepancies
+type Reconciler struct {
+	store db.Store
+}
+
+// New creates a Reconciler reading from the given store
+func New(store db.Store) *Reconciler {
+	return &Reconciler{store: store}
+}
+
+// Run scans all accounts and transfers in batches and reports every
+// discrepancy it finds.
+func (r *Reconciler) Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	report := Report{
+		Accounts:      []AccountDiscrepancy{},
+		Transfers:     []TransferDiscrepancy{},
+		OrphanEntries: []OrphanEntry{},
+	}
+
+	if err := r.scanAccounts(ctx, opts, &report); err != nil {
+		return report, err
+	}
+	if err := r.scanTransfers(ctx, opts, &report); err != nil {
+		return report, err
+	}
+	if err := r.scanOrphanEntries(ctx, opts, &report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (r *Reconciler) scanAccounts(ctx context.Context, opts Options, report *Report) error {
+	var afterID int64
+	for {
+		rows, err := r.store.ListAccountLedgerTotalsAfter(ctx, db.ListAccountLedgerTotalsAfterParams{
+			AfterID: afterID,
+			Limit:   opts.BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot scan accounts after %d: %w", afterID, err)
+		}
+
+		for _, row := range rows {
+			if !opts.covers(row.ID) {
+				continue
+			}
+			report.AccountsScanned++
+			if row.Balance == row.EntriesTotal {
+				continue
+			}
+
+			discrepancy := AccountDiscrepancy{
+				AccountID:       row.ID,
+				StoredBalance:   row.Balance,
+				ComputedBalance: row.EntriesTotal,
+			}
+
+			if opts.Fix {
+				result, err := r.store.ReconcileAccountTx(ctx, row.ID)
+				if err != nil {
+					return fmt.Errorf("cannot correct account %d: %w", row.ID, err)
+				}
+				discrepancy.CorrectionEntryID = result.Entry.ID
+			}
+
+			report.Accounts = append(report.Accounts, discrepancy)
+		}
+
+		if len(rows) < int(opts.BatchSize) {
+			return nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+func (r *Reconciler) scanTransfers(ctx context.Context, opts Options, report *Report) error {
+	var afterID int64
+	for {
+		rows, err := r.store.ListTransferEntryCountsAfter(ctx, db.ListTransferEntryCountsAfterParams{
+			AfterID: afterID,
+			Limit:   opts.BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot scan transfers after %d: %w", afterID, err)
+		}
+
+		for _, row := range rows {
+			if !opts.covers(row.FromAccountID, row.ToAccountID) {
+				continue
+			}
+			report.TransfersScanned++
+
+			// authorized and failed transfers haven't moved any money
+			var want int64
+			if row.Status.Posted() {
+				want = 1
+			}
+			if row.DebitEntries == want && row.CreditEntries == want {
+				continue
+			}
+
+			report.Transfers = append(report.Transfers, TransferDiscrepancy{
+				TransferID:    row.ID,
+				FromAccountID: row.FromAccountID,
+				ToAccountID:   row.ToAccountID,
+				Status:        row.Status,
+				DebitEntries:  row.DebitEntries,
+				CreditEntries: row.CreditEntries,
+			})
+		}
+
+		if len(rows) < int(opts.BatchSize) {
+			return nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+func (r *Reconciler) scanOrphanEntries(ctx context.Context, opts Options, report *Report) error {
+	var afterID int64
+	for {
+		entries, err := r.store.ListOrphanEntriesAfter(ctx, db.ListOrphanEntriesAfterParams{
+			AfterID: afterID,
+			Limit:   opts.BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot scan entries after %d: %w", afterID, err)
+		}
+
+		for _, entry := range entries {
+			if !opts.covers(entry.AccountID) {
+				continue
+			}
+			report.OrphanEntries = append(report.OrphanEntries, OrphanEntry{
+				EntryID:    entry.ID,
+				AccountID:  entry.AccountID,
+				Amount:     entry.Amount,
+				EntryType:  entry.EntryType,
+				TransferID: entry.TransferID,
+			})
+		}
+
+		if len(entries) < int(opts.BatchSize) {
+			return nil
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
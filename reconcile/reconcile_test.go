@@ -0,0 +1,232 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func createAccount(t *testing.T, balance int64) db.Account {
+	user, err := testQueries.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	account, err := testQueries.CreateAccount(context.Background(), db.CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func findAccount(report Report, accountID int64) (AccountDiscrepancy, bool) {
+	for _, d := range report.Accounts {
+		if d.AccountID == accountID {
+			return d, true
+		}
+	}
+	return AccountDiscrepancy{}, false
+}
+
+func findTransfer(report Report, transferID int64) (TransferDiscrepancy, bool) {
+	for _, d := range report.Transfers {
+		if d.TransferID == transferID {
+			return d, true
+		}
+	}
+	return TransferDiscrepancy{}, false
+}
+
+func TestRunDetectsCorruption(t *testing.T) {
+	store := db.NewStore(testDB, nil)
+
+	// a balance written without any entry behind it
+	corrupted := createAccount(t, 500)
+
+	// a balanced account: its only entry matches the balance
+	balanced := createAccount(t, 0)
+	_, err := store.DepositTx(context.Background(), db.DepositTxParams{AccountID: balanced.ID, Amount: 70})
+	require.NoError(t, err)
+
+	// a transfer row with no entries at all
+	orphan, err := testQueries.CreateTransfer(context.Background(), db.CreateTransferParams{
+		FromAccountID: corrupted.ID,
+		ToAccountID:   balanced.ID,
+		Amount:        10,
+		ToAmount:      10,
+		ExchangeRate:  "1",
+		Status:        db.TransferStatusSettled,
+	})
+	require.NoError(t, err)
+
+	// a transfer leg that doesn't say which transfer it belongs to
+	unlinked, err := testQueries.CreateEntry(context.Background(), db.CreateEntryParams{
+		AccountID: balanced.ID,
+		Amount:    10,
+		EntryType: db.EntryTypeTransferCredit,
+	})
+	require.NoError(t, err)
+
+	report, err := New(store).Run(context.Background(), Options{
+		BatchSize:  5,
+		AccountIDs: []int64{corrupted.ID, balanced.ID},
+	})
+	require.NoError(t, err)
+	require.False(t, report.OK())
+
+	discrepancy, ok := findAccount(report, corrupted.ID)
+	require.True(t, ok)
+	require.Equal(t, int64(500), discrepancy.StoredBalance)
+	require.Equal(t, int64(0), discrepancy.ComputedBalance)
+	require.Zero(t, discrepancy.CorrectionEntryID)
+
+	_, ok = findAccount(report, balanced.ID)
+	require.False(t, ok)
+
+	transfer, ok := findTransfer(report, orphan.ID)
+	require.True(t, ok)
+	require.Zero(t, transfer.DebitEntries)
+	require.Zero(t, transfer.CreditEntries)
+
+	var found bool
+	for _, orphan := range report.OrphanEntries {
+		if orphan.EntryID == unlinked.ID {
+			found = true
+			require.Equal(t, db.EntryTypeTransferCredit, orphan.EntryType)
+			require.Nil(t, orphan.TransferID)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestRunFix(t *testing.T) {
+	store := db.NewStore(testDB, nil)
+	corrupted := createAccount(t, 250)
+
+	// other tests share the database, so only this test's account is fixed
+	report, err := New(store).Run(context.Background(), Options{
+		Fix:        true,
+		AccountIDs: []int64{corrupted.ID},
+	})
+	require.NoError(t, err)
+
+	discrepancy, ok := findAccount(report, corrupted.ID)
+	require.True(t, ok)
+	require.NotZero(t, discrepancy.CorrectionEntryID)
+
+	total, err := testQueries.GetAccountEntriesTotal(context.Background(), corrupted.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(250), total)
+
+	// once corrected, the account is no longer reported
+	report, err = New(store).Run(context.Background(), Options{AccountIDs: []int64{corrupted.ID}})
+	require.NoError(t, err)
+	_, ok = findAccount(report, corrupted.ID)
+	require.False(t, ok)
+}
+
+func TestRunBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountLedgerTotalsAfter(gomock.Any(), db.ListAccountLedgerTotalsAfterParams{AfterID: 0, Limit: 2}).
+			Return([]db.ListAccountLedgerTotalsAfterRow{
+				{ID: 1, Balance: 10, EntriesTotal: 10},
+				{ID: 2, Balance: 20, EntriesTotal: 5},
+			}, nil),
+		store.EXPECT().
+			ListAccountLedgerTotalsAfter(gomock.Any(), db.ListAccountLedgerTotalsAfterParams{AfterID: 2, Limit: 2}).
+			Return([]db.ListAccountLedgerTotalsAfterRow{
+				{ID: 3, Balance: 0, EntriesTotal: 0},
+			}, nil),
+	)
+	store.EXPECT().ReconcileAccountTx(gomock.Any(), gomock.Eq(int64(2))).
+		Times(1).Return(db.AccountTxResult{Entry: db.Entry{ID: 99, AccountID: 2, Amount: 15}}, nil)
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListTransferEntryCountsAfter(gomock.Any(), db.ListTransferEntryCountsAfterParams{AfterID: 0, Limit: 2}).
+			Return([]db.ListTransferEntryCountsAfterRow{
+				{ID: 1, FromAccountID: 1, ToAccountID: 2, Status: db.TransferStatusSettled, DebitEntries: 1, CreditEntries: 2},
+				// an authorization has no entries until it is captured
+				{ID: 2, FromAccountID: 1, ToAccountID: 2, Status: db.TransferStatusAuthorized},
+			}, nil),
+		store.EXPECT().
+			ListTransferEntryCountsAfter(gomock.Any(), db.ListTransferEntryCountsAfterParams{AfterID: 2, Limit: 2}).
+			Return([]db.ListTransferEntryCountsAfterRow{}, nil),
+	)
+
+	transferID := int64(7)
+	store.EXPECT().
+		ListOrphanEntriesAfter(gomock.Any(), db.ListOrphanEntriesAfterParams{AfterID: 0, Limit: 2}).
+		Return([]db.Entry{
+			{ID: 4, AccountID: 1, Amount: 5, EntryType: db.EntryTypeDeposit, TransferID: &transferID},
+		}, nil)
+
+	report, err := New(store).Run(context.Background(), Options{BatchSize: 2, Fix: true})
+	require.NoError(t, err)
+
+	require.Equal(t, 3, report.AccountsScanned)
+	require.Equal(t, 2, report.TransfersScanned)
+	require.Equal(t, []AccountDiscrepancy{
+		{AccountID: 2, StoredBalance: 20, ComputedBalance: 5, CorrectionEntryID: 99},
+	}, report.Accounts)
+	require.Equal(t, []TransferDiscrepancy{
+		{TransferID: 1, FromAccountID: 1, ToAccountID: 2, Status: db.TransferStatusSettled, DebitEntries: 1, CreditEntries: 2},
+	}, report.Transfers)
+	require.Equal(t, []OrphanEntry{
+		{EntryID: 4, AccountID: 1, Amount: 5, EntryType: db.EntryTypeDeposit, TransferID: &transferID},
+	}, report.OrphanEntries)
+}
+
+func TestRunAccountIDs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().ListAccountLedgerTotalsAfter(gomock.Any(), gomock.Any()).
+		Return([]db.ListAccountLedgerTotalsAfterRow{
+			{ID: 1, Balance: 10, EntriesTotal: 0},
+			{ID: 2, Balance: 20, EntriesTotal: 5},
+		}, nil)
+	// account 1 is out of scope and must be left alone
+	store.EXPECT().ReconcileAccountTx(gomock.Any(), gomock.Eq(int64(1))).Times(0)
+	store.EXPECT().ReconcileAccountTx(gomock.Any(), gomock.Eq(int64(2))).
+		Times(1).Return(db.AccountTxResult{Entry: db.Entry{ID: 99, AccountID: 2, Amount: 15}}, nil)
+
+	store.EXPECT().ListTransferEntryCountsAfter(gomock.Any(), gomock.Any()).
+		Return([]db.ListTransferEntryCountsAfterRow{
+			{ID: 1, FromAccountID: 1, ToAccountID: 3, Status: db.TransferStatusSettled},
+			{ID: 2, FromAccountID: 3, ToAccountID: 2, Status: db.TransferStatusSettled},
+		}, nil)
+	store.EXPECT().ListOrphanEntriesAfter(gomock.Any(), gomock.Any()).
+		Return([]db.Entry{
+			{ID: 4, AccountID: 1, Amount: 5, EntryType: db.EntryTypeTransferCredit},
+		}, nil)
+
+	report, err := New(store).Run(context.Background(), Options{Fix: true, AccountIDs: []int64{2}})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, report.AccountsScanned)
+	require.Equal(t, 1, report.TransfersScanned)
+	require.Equal(t, []AccountDiscrepancy{
+		{AccountID: 2, StoredBalance: 20, ComputedBalance: 5, CorrectionEntryID: 99},
+	}, report.Accounts)
+	require.Equal(t, []TransferDiscrepancy{
+		{TransferID: 2, FromAccountID: 3, ToAccountID: 2, Status: db.TransferStatusSettled},
+	}, report.Transfers)
+	require.Empty(t, report.OrphanEntries)
+}
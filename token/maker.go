@@ -0,0 +1,13 @@
+package token
+
+import "time"
+
+// Maker is an interface for managing tokens
+type Maker interface {
+	// CreateToken creates a new token of the given type for a specific
+	// username and duration
+	CreateToken(username string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
+
+	// VerifyToken checks if the token is valid and of the given type
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
+}
@@ -0,0 +1,93 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoMaker(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, TokenTypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccess)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoToken(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccess)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoTokenWrongKey(t *testing.T) {
+	maker1, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+	maker2, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err := maker1.CreateToken(util.RandomOwner(), TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker2.VerifyToken(token, TokenTypeAccess)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoMakerInvalidKeySize(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(31))
+	require.Error(t, err)
+	require.Nil(t, maker)
+}
+
+func TestPasetoTokenWrongType(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	refreshToken, _, err := maker.CreateToken(util.RandomOwner(), TokenTypeRefresh, time.Minute)
+	require.NoError(t, err)
+	accessToken, _, err := maker.CreateToken(util.RandomOwner(), TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(refreshToken, TokenTypeAccess)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	payload, err = maker.VerifyToken(accessToken, TokenTypeRefresh)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	payload, err = maker.VerifyToken(refreshToken, TokenTypeRefresh)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeRefresh, payload.Type)
+}
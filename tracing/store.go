@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes recorded on the spans of store transactions
+const (
+	TxKey            = attribute.Key("bank.tx")
+	AccountIDKey     = attribute.Key("bank.account_id")
+	FromAccountIDKey = attribute.Key("bank.from_account_id")
+	ToAccountIDKey   = attribute.Key("bank.to_account_id")
+	TransferIDKey    = attribute.Key("bank.transfer_id")
+	AmountKey        = attribute.Key("bank.amount")
+	BatchSizeKey     = attribute.Key("bank.batch_size")
+)
+
+// Store is a db.Store that records a span for each of its transactions,
+// naming the accounts and amount involved. Single queries are traced by the
+// SQLStore itself, one span per statement, so they pass straight through.
+type Store struct {
+	db.Store
+	tracer trace.Tracer
+}
+
+// NewStore wraps store, creating spans with provider; a nil provider uses
+// the global one.
+func NewStore(store db.Store, provider trace.TracerProvider) *Store {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Store{Store: store, tracer: provider.Tracer(db.TracerName)}
+}
+
+func (s *Store) start(ctx context.Context, tx string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "Store."+tx, trace.WithAttributes(append(attrs, TxKey.String(tx))...))
+}
+
+// end ends span, marking it failed with err, if any
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (result db.TransferTxResult, err error) {
+	ctx, span := s.start(ctx, "TransferTx",
+		FromAccountIDKey.Int64(arg.FromAccountID),
+		ToAccountIDKey.Int64(arg.ToAccountID),
+		AmountKey.Int64(arg.Amount),
+	)
+	defer func() { end(span, err) }()
+
+	result, err = s.Store.TransferTx(ctx, arg)
+	if err == nil {
+		span.SetAttributes(TransferIDKey.Int64(result.Transfer.ID))
+	}
+	return result, err
+}
+
+func (s *Store) BatchTransferTx(ctx context.Context, arg db.BatchTransferTxParams) (result db.BatchTransferTxResult, err error) {
+	ctx, span := s.start(ctx, "BatchTransferTx",
+		FromAccountIDKey.Int64(arg.FromAccountID),
+		BatchSizeKey.Int(len(arg.Items)),
+	)
+	defer func() { end(span, err) }()
+
+	return s.Store.BatchTransferTx(ctx, arg)
+}
+
+func (s *Store) ReverseTransferTx(ctx context.Context, arg db.ReverseTransferTxParams) (result db.ReverseTransferTxResult, err error) {
+	ctx, span := s.start(ctx, "ReverseTransferTx",
+		TransferIDKey.Int64(arg.TransferID),
+		AmountKey.Int64(arg.Amount),
+	)
+	defer func() { end(span, err) }()
+
+	return s.Store.ReverseTransferTx(ctx, arg)
+}
+
+func (s *Store) AuthorizeTransferTx(ctx context.Context, arg db.AuthorizeTransferTxParams) (result db.HoldTxResult, err error) {
+	ctx, span := s.start(ctx, "AuthorizeTransferTx",
+		FromAccountIDKey.Int64(arg.FromAccountID),
+		ToAccountIDKey.Int64(arg.ToAccountID),
+		AmountKey.Int64(arg.Amount),
+	)
+	defer func() { end(span, err) }()
+
+	result, err = s.Store.AuthorizeTransferTx(ctx, arg)
+	if err == nil {
+		span.SetAttributes(TransferIDKey.Int64(result.Transfer.ID))
+	}
+	return result, err
+}
+
+func (s *Store) CaptureTransferTx(ctx context.Context, transferID int64) (result db.TransferTxResult, err error) {
+	ctx, span := s.start(ctx, "CaptureTransferTx", TransferIDKey.Int64(transferID))
+	defer func() { end(span, err) }()
+
+	return s.Store.CaptureTransferTx(ctx, transferID)
+}
+
+func (s *Store) VoidTransferTx(ctx context.Context, transferID int64) (result db.HoldTxResult, err error) {
+	ctx, span := s.start(ctx, "VoidTransferTx", TransferIDKey.Int64(transferID))
+	defer func() { end(span, err) }()
+
+	return s.Store.VoidTransferTx(ctx, transferID)
+}
+
+func (s *Store) DepositTx(ctx context.Context, arg db.DepositTxParams) (result db.AccountTxResult, err error) {
+	ctx, span := s.start(ctx, "DepositTx", AccountIDKey.Int64(arg.AccountID), AmountKey.Int64(arg.Amount))
+	defer func() { end(span, err) }()
+
+	return s.Store.DepositTx(ctx, arg)
+}
+
+func (s *Store) WithdrawTx(ctx context.Context, arg db.WithdrawTxParams) (result db.AccountTxResult, err error) {
+	ctx, span := s.start(ctx, "WithdrawTx", AccountIDKey.Int64(arg.AccountID), AmountKey.Int64(arg.Amount))
+	defer func() { end(span, err) }()
+
+	return s.Store.WithdrawTx(ctx, arg)
+}
+
+func (s *Store) AdjustBalanceTx(ctx context.Context, arg db.AdjustBalanceTxParams) (result db.AccountTxResult, err error) {
+	ctx, span := s.start(ctx, "AdjustBalanceTx", AccountIDKey.Int64(arg.AccountID))
+	defer func() { end(span, err) }()
+
+	return s.Store.AdjustBalanceTx(ctx, arg)
+}
+
+func (s *Store) ReconcileAccountTx(ctx context.Context, accountID int64) (result db.AccountTxResult, err error) {
+	ctx, span := s.start(ctx, "ReconcileAccountTx", AccountIDKey.Int64(accountID))
+	defer func() { end(span, err) }()
+
+	return s.Store.ReconcileAccountTx(ctx, accountID)
+}
+
+// RunScheduledTransferTx doesn't mark the span failed when nothing is due,
+// since the scheduler polls for it.
+func (s *Store) RunScheduledTransferTx(ctx context.Context, now time.Time) (result db.RunScheduledTransferTxResult, err error) {
+	ctx, span := s.start(ctx, "RunScheduledTransferTx")
+	defer func() {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.End()
+			return
+		}
+		end(span, err)
+	}()
+
+	result, err = s.Store.RunScheduledTransferTx(ctx, now)
+	if err == nil {
+		span.SetAttributes(
+			FromAccountIDKey.Int64(result.ScheduledTransfer.FromAccountID),
+			ToAccountIDKey.Int64(result.ScheduledTransfer.ToAccountID),
+			AmountKey.Int64(result.ScheduledTransfer.Amount),
+		)
+	}
+	return result, err
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestStore(t *testing.T) (*Store, *mockdb.MockStore, *tracetest.SpanRecorder) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	mock := mockdb.NewMockStore(ctrl)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewStore(mock, provider), mock, recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestStoreTracesTransfer(t *testing.T) {
+	store, mock, recorder := newTestStore(t)
+	arg := db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 10}
+
+	mock.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).
+		DoAndReturn(func(ctx context.Context, _ db.TransferTxParams) (db.TransferTxResult, error) {
+			// Statements run by the store must nest under the transaction's span
+			require.True(t, trace.SpanContextFromContext(ctx).IsValid())
+			return db.TransferTxResult{Transfer: db.Transfer{ID: 7}}, nil
+		})
+
+	_, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "Store.TransferTx", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	attrs := spanAttributes(spans[0])
+	require.Equal(t, "TransferTx", attrs[TxKey].AsString())
+	require.EqualValues(t, 1, attrs[FromAccountIDKey].AsInt64())
+	require.EqualValues(t, 2, attrs[ToAccountIDKey].AsInt64())
+	require.EqualValues(t, 10, attrs[AmountKey].AsInt64())
+	require.EqualValues(t, 7, attrs[TransferIDKey].AsInt64())
+}
+
+func TestStoreTracesFailure(t *testing.T) {
+	store, mock, recorder := newTestStore(t)
+
+	mock.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).
+		Return(db.AccountTxResult{}, db.ErrInsufficientFunds)
+
+	_, err := store.WithdrawTx(context.Background(), db.WithdrawTxParams{AccountID: 3, Amount: 50})
+	require.ErrorIs(t, err, db.ErrInsufficientFunds)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.EqualValues(t, 3, spanAttributes(spans[0])[AccountIDKey].AsInt64())
+}
+
+func TestStoreNothingScheduledIsNotAFailure(t *testing.T) {
+	store, mock, recorder := newTestStore(t)
+
+	mock.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+		Return(db.RunScheduledTransferTxResult{}, db.ErrRecordNotFound)
+
+	_, err := store.RunScheduledTransferTx(context.Background(), time.Now())
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+}
@@ -0,0 +1,74 @@
+// Package tracing sets up OpenTelemetry tracing for the bank and traces the
+// store's transactions.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NoahFola/simple_bank/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies the bank in the traces it exports
+const ServiceName = "simple_bank"
+
+// Supported trace exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Setup installs the W3C trace context propagator and, unless the exporter
+// is none, a tracer provider exporting to stdout or to an OTLP/HTTP
+// collector at config.OTLPEndpoint. A share config.TraceSampleRatio of new
+// traces is sampled, which must lie between 0 and 1; requests that arrive
+// with a sampled parent are always traced. The returned function flushes
+// and stops the exporter.
+func Setup(ctx context.Context, config util.Config) (shutdown func(context.Context) error, err error) {
+	ratio := config.TraceSampleRatio
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("trace sample ratio %v is not between 0 and 1", ratio)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch strings.ToLower(config.TraceExporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpoint(config.OTLPEndpoint),
+			otlptracehttp.WithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", config.TraceExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s trace exporter: %w", config.TraceExporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
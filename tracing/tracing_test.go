@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NoahFola/simple_bank/util"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	for _, exporter := range []string{"", ExporterNone, ExporterStdout} {
+		shutdown, err := Setup(context.Background(), util.Config{TraceExporter: exporter})
+		require.NoError(t, err, exporter)
+		require.NoError(t, shutdown(context.Background()))
+	}
+	require.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+
+	_, err := Setup(context.Background(), util.Config{TraceExporter: "zipkin"})
+	require.Error(t, err)
+}
+
+func TestSetupSampleRatio(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	// zero samples no new traces rather than falling back to all of them
+	shutdown, err := Setup(context.Background(), util.Config{TraceExporter: ExporterStdout, TraceSampleRatio: 0})
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "request")
+	require.False(t, span.SpanContext().IsSampled())
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	for _, ratio := range []float64{-0.1, 1.5} {
+		_, err := Setup(context.Background(), util.Config{TraceExporter: ExporterStdout, TraceSampleRatio: ratio})
+		require.Error(t, err, ratio)
+	}
+}
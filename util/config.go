@@ -9,11 +9,40 @@ import (
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variables.
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                string        `mapstructure:"DB_DRIVER"`
+	DBSource                string        `mapstructure:"DB_SOURCE"`
+	DBMaxOpenConns          int           `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns          int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime       time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime       time.Duration `mapstructure:"DB_CONN_MAX_IDLE_TIME"`
+	DBConnectTimeout        time.Duration `mapstructure:"DB_CONNECT_TIMEOUT"`
+	MigrateOnStart          bool          `mapstructure:"MIGRATE_ON_START"`
+	ServerAddress           string        `mapstructure:"SERVER_ADDRESS"`
+	MetricsAddress          string        `mapstructure:"METRICS_ADDRESS"`
+	HTTPReadHeaderTimeout   time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPReadTimeout         time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout        time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout         time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	HTTPMaxHeaderBytes      int           `mapstructure:"HTTP_MAX_HEADER_BYTES"`
+	ShutdownTimeout         time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	TokenSymmetricKey       string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration     time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration    time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	IdempotencyKeyRetention time.Duration `mapstructure:"IDEMPOTENCY_KEY_RETENTION"`
+	FXRatesFile             string        `mapstructure:"FX_RATES_FILE"`
+	HoldDuration            time.Duration `mapstructure:"HOLD_DURATION"`
+	HoldExpiryInterval      time.Duration `mapstructure:"HOLD_EXPIRY_INTERVAL"`
+	SchedulerInterval       time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	LogLevel                string        `mapstructure:"LOG_LEVEL"`
+	LogFormat               string        `mapstructure:"LOG_FORMAT"`
+	TraceExporter           string        `mapstructure:"TRACE_EXPORTER"`
+	OTLPEndpoint            string        `mapstructure:"OTLP_ENDPOINT"`
+	TraceSampleRatio        float64       `mapstructure:"TRACE_SAMPLE_RATIO"`
+
+	// SchemaVersion is the migration the database must be at for the server
+	// to be ready. It is not read by viper: main sets it from the embedded
+	// migrations, and zero accepts any version.
+	SchemaVersion int64 `mapstructure:"-"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -25,6 +54,9 @@ func LoadConfig(path string) (config Config, err error) {
 	// Enable reading from environment variables
 	viper.AutomaticEnv()
 
+	// Trace every request unless a ratio is configured; 0 turns sampling off
+	viper.SetDefault("TRACE_SAMPLE_RATIO", 1)
+
 	err = viper.ReadInConfig()
 	if err != nil {
 		return
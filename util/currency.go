@@ -0,0 +1,18 @@
+package util
+
+import "slices"
+
+// Currencies accounts can hold
+const (
+	USD = "USD"
+	EUR = "EUR"
+	CAD = "CAD"
+)
+
+// SupportedCurrencies lists every currency accounts can hold
+var SupportedCurrencies = []string{USD, EUR, CAD}
+
+// IsSupportedCurrency reports whether accounts can hold currency
+func IsSupportedCurrency(currency string) bool {
+	return slices.Contains(SupportedCurrencies, currency)
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/NoahFola/simple_bank/apperr"
+)
+
+// ErrUnsupportedCurrencyPair is returned when no exchange rate is known for a pair
+var ErrUnsupportedCurrencyPair = apperr.New(apperr.Unprocessable, "unsupported currency pair")
+
+// FXQuote is the rate at which one unit of From converts into units of To.
+// Rate is a decimal string such as "0.9215" so it can be stored without loss.
+type FXQuote struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Rate string `json:"rate"`
+}
+
+// Convert applies the quote to an amount in the From currency, rounding the
+// result half away from zero to the nearest unit of the To currency.
+func (q FXQuote) Convert(amount int64) (int64, error) {
+	rate, ok := new(big.Rat).SetString(q.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid exchange rate %q", q.Rate)
+	}
+
+	product := new(big.Rat).Mul(rate, new(big.Rat).SetInt64(amount))
+
+	// round half away from zero: (2*num + sign*den) / (2*den)
+	num := new(big.Int).Mul(product.Num(), big.NewInt(2))
+	den := new(big.Int).Mul(product.Denom(), big.NewInt(2))
+	num.Add(num, new(big.Int).Mul(product.Denom(), big.NewInt(int64(product.Sign()))))
+	converted := new(big.Int).Quo(num, den)
+
+	if !converted.IsInt64() {
+		return 0, fmt.Errorf("converted amount overflows: %s", converted)
+	}
+	return converted.Int64(), nil
+}
+
+// FXRateProvider quotes exchange rates between currencies
+type FXRateProvider interface {
+	Quote(ctx context.Context, from, to string) (FXQuote, error)
+}
+
+// StaticFXRateProvider serves a fixed set of rates, keyed as "FROM/TO"
+type StaticFXRateProvider struct {
+	rates map[string]string
+}
+
+// NewStaticFXRateProvider creates a provider from rates keyed as "FROM/TO",
+// e.g. {"USD/EUR": "0.92"}. The inverse pair is not derived automatically.
+func NewStaticFXRateProvider(rates map[string]string) (*StaticFXRateProvider, error) {
+	normalized := make(map[string]string, len(rates))
+	for pair, rate := range rates {
+		from, to, ok := strings.Cut(strings.ToUpper(pair), "/")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid currency pair %q: must be FROM/TO", pair)
+		}
+
+		if _, err := (FXQuote{From: from, To: to, Rate: rate}).Convert(1); err != nil {
+			return nil, fmt.Errorf("currency pair %s: %w", pair, err)
+		}
+		normalized[from+"/"+to] = rate
+	}
+
+	return &StaticFXRateProvider{rates: normalized}, nil
+}
+
+// LoadFXRatesFile creates a StaticFXRateProvider from a JSON file holding
+// an object of "FROM/TO": "rate" entries.
+func LoadFXRatesFile(path string) (*StaticFXRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates map[string]string
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("cannot parse fx rates file: %w", err)
+	}
+
+	return NewStaticFXRateProvider(rates)
+}
+
+// Quote returns the rate from one currency to another. A currency always
+// converts to itself at a rate of 1.
+func (p *StaticFXRateProvider) Quote(ctx context.Context, from, to string) (FXQuote, error) {
+	if from == to {
+		return FXQuote{From: from, To: to, Rate: "1"}, nil
+	}
+
+	rate, ok := p.rates[from+"/"+to]
+	if !ok {
+		return FXQuote{}, fmt.Errorf("%w: %s/%s", ErrUnsupportedCurrencyPair, from, to)
+	}
+	return FXQuote{From: from, To: to, Rate: rate}, nil
+}
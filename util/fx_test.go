@@ -0,0 +1,73 @@
+package util
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFXQuoteConvert(t *testing.T) {
+	testCases := []struct {
+		rate     string
+		amount   int64
+		expected int64
+	}{
+		{rate: "1", amount: 100, expected: 100},
+		{rate: "0.925", amount: 100, expected: 93},
+		{rate: "0.924", amount: 100, expected: 92},
+		{rate: "1.36", amount: 25, expected: 34},
+		{rate: "0.5", amount: 1, expected: 1},
+	}
+
+	for _, tc := range testCases {
+		converted, err := FXQuote{From: USD, To: EUR, Rate: tc.rate}.Convert(tc.amount)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, converted, "rate %s amount %d", tc.rate, tc.amount)
+	}
+
+	for _, rate := range []string{"", "abc", "0", "-1.2"} {
+		_, err := FXQuote{From: USD, To: EUR, Rate: rate}.Convert(100)
+		require.Error(t, err, "rate %q", rate)
+	}
+}
+
+func TestStaticFXRateProvider(t *testing.T) {
+	provider, err := NewStaticFXRateProvider(map[string]string{"usd/eur": "0.92"})
+	require.NoError(t, err)
+
+	quote, err := provider.Quote(context.Background(), USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, FXQuote{From: USD, To: EUR, Rate: "0.92"}, quote)
+
+	quote, err = provider.Quote(context.Background(), CAD, CAD)
+	require.NoError(t, err)
+	require.Equal(t, "1", quote.Rate)
+
+	_, err = provider.Quote(context.Background(), EUR, USD)
+	require.ErrorIs(t, err, ErrUnsupportedCurrencyPair)
+
+	_, err = NewStaticFXRateProvider(map[string]string{"USDEUR": "0.92"})
+	require.Error(t, err)
+
+	_, err = NewStaticFXRateProvider(map[string]string{"USD/EUR": "-0.92"})
+	require.Error(t, err)
+}
+
+func TestLoadFXRatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fx_rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD/CAD": "1.36"}`), 0o600))
+
+	provider, err := LoadFXRatesFile(path)
+	require.NoError(t, err)
+
+	quote, err := provider.Quote(context.Background(), USD, CAD)
+	require.NoError(t, err)
+	require.Equal(t, "1.36", quote.Rate)
+
+	require.NoError(t, os.WriteFile(path, []byte(`not json`), 0o600))
+	_, err = LoadFXRatesFile(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "info", LogFormatJSON)
+	require.NoError(t, err)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	logger.DebugContext(ctx, "hidden")
+	logger.With("account_id", 7).InfoContext(ctx, "shown")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "shown", record["msg"])
+	require.Equal(t, "INFO", record["level"])
+	require.Equal(t, "req-1", record["request_id"])
+	require.Equal(t, float64(7), record["account_id"])
+}
+
+func TestNewLoggerText(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "DEBUG", LogFormatText)
+	require.NoError(t, err)
+
+	logger.DebugContext(context.Background(), "shown")
+	require.Contains(t, buf.String(), "level=DEBUG msg=shown")
+	require.NotContains(t, buf.String(), "request_id")
+}
+
+func TestNewLoggerInvalid(t *testing.T) {
+	_, err := NewLogger(&bytes.Buffer{}, "loud", LogFormatText)
+	require.Error(t, err)
+
+	_, err = NewLogger(&bytes.Buffer{}, "info", "xml")
+	require.Error(t, err)
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	require.Empty(t, RequestIDFromContext(context.Background()))
+
+	ctx := ContextWithRequestID(context.Background(), "req-2")
+	require.Equal(t, "req-2", RequestIDFromContext(ctx))
+}
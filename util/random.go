@@ -9,13 +9,6 @@ import (
 
 const alphabet = "abcdefghijklmnopqrstuvwxyz"
 
-// Define currency constants
-const (
-	USD = "USD"
-	EUR = "EUR"
-	CAD = "CAD"
-)
-
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -50,9 +43,8 @@ func RandomMoney() int64 {
 
 // RandomCurrency generates a random currency code
 func RandomCurrency() string {
-	currencies := []string{USD, EUR, CAD}
-	n := len(currencies)
-	return currencies[rand.Intn(n)]
+	n := len(SupportedCurrencies)
+	return SupportedCurrencies[rand.Intn(n)]
 }
 
 // RandomEmail generates a random email
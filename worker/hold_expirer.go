@@ -0,0 +1,106 @@
+// Package worker runs the background jobs of the bank alongside the API
+// server.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/util"
+)
+
+const (
+	defaultExpiryInterval  = time.Minute
+	defaultExpiryBatchSize = 100
+)
+
+// HoldExpirer voids authorized transfers whose hold has lapsed, giving the
+// held amount back to the available balance of the source account.
+type HoldExpirer struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+	logger    *slog.Logger
+}
+
+// NewHoldExpirer creates a HoldExpirer that checks for expired holds every
+// interval, defaulting to once a minute. Outcomes are logged to logger; a
+// nil logger discards them.
+func NewHoldExpirer(store db.Store, interval time.Duration, logger *slog.Logger) *HoldExpirer {
+	if interval <= 0 {
+		interval = defaultExpiryInterval
+	}
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+
+	return &HoldExpirer{
+		store:     store,
+		interval:  interval,
+		batchSize: defaultExpiryBatchSize,
+		logger:    logger.With("worker", "hold_expirer"),
+	}
+}
+
+// Run expires holds until the context is cancelled
+func (e *HoldExpirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := e.ExpireHolds(ctx, now)
+			if err != nil {
+				e.logger.ErrorContext(ctx, "cannot expire holds", "error", err)
+			}
+			if expired > 0 {
+				e.logger.InfoContext(ctx, "expired holds", "expired", expired)
+			}
+		}
+	}
+}
+
+// ExpireHolds voids one batch of transfers whose hold expired at or before
+// now, returning how many were voided. A hold that cannot be voided is
+// logged and skipped so it does not hold up the rest of the batch; the
+// errors are returned joined once the batch is done.
+func (e *HoldExpirer) ExpireHolds(ctx context.Context, now time.Time) (int, error) {
+	holds, err := e.store.ListExpiredHolds(ctx, db.ListExpiredHoldsParams{
+		ExpiredAt: now,
+		Limit:     e.batchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list expired holds: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, hold := range holds {
+		_, err := e.store.VoidTransferTx(ctx, hold.TransferID)
+		if err != nil {
+			// captured or voided since the holds were listed
+			if errors.Is(err, db.ErrTransferNotAuthorized) {
+				continue
+			}
+			// nothing more can be voided once the context is done
+			if ctx.Err() != nil {
+				errs = append(errs, ctx.Err())
+				break
+			}
+			e.logger.ErrorContext(ctx, "cannot void expired hold", "transfer_id", hold.TransferID, "error", err)
+			errs = append(errs, fmt.Errorf("cannot void transfer %d: %w", hold.TransferID, err))
+			continue
+		}
+		e.logger.DebugContext(ctx, "hold expired", "transfer_id", hold.TransferID, "account_id", hold.AccountID)
+		expired++
+	}
+
+	return expired, errors.Join(errs...)
+}
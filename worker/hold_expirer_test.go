@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireHolds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.EXPECT().
+		ListExpiredHolds(gomock.Any(), db.ListExpiredHoldsParams{ExpiredAt: now, Limit: defaultExpiryBatchSize}).
+		Times(1).
+		Return([]db.Hold{{ID: 1, TransferID: 10}, {ID: 2, TransferID: 11}}, nil)
+
+	store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(int64(10))).
+		Times(1).Return(db.HoldTxResult{}, nil)
+	// captured after the holds were listed
+	store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(int64(11))).
+		Times(1).Return(db.HoldTxResult{}, db.ErrTransferNotAuthorized)
+
+	expired, err := NewHoldExpirer(store, 0, nil).ExpireHolds(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 1, expired)
+}
+
+func TestExpireHoldsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().ListExpiredHolds(gomock.Any(), gomock.Any()).
+		Times(1).Return([]db.Hold{{ID: 1, TransferID: 10}, {ID: 2, TransferID: 11}}, nil)
+	store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(int64(10))).
+		Times(1).Return(db.HoldTxResult{}, sql.ErrConnDone)
+	// a hold that cannot be voided does not block the ones behind it
+	store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(int64(11))).
+		Times(1).Return(db.HoldTxResult{}, nil)
+
+	expired, err := NewHoldExpirer(store, time.Second, nil).ExpireHolds(context.Background(), time.Now())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.ErrorContains(t, err, "transfer 10")
+	require.Equal(t, 1, expired)
+}
+
+func TestExpireHoldsCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.EXPECT().ListExpiredHolds(gomock.Any(), gomock.Any()).
+		Times(1).Return([]db.Hold{{ID: 1, TransferID: 10}, {ID: 2, TransferID: 11}}, nil)
+	store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(int64(10))).
+		Times(1).
+		DoAndReturn(func(context.Context, int64) (db.HoldTxResult, error) {
+			cancel()
+			return db.HoldTxResult{}, context.Canceled
+		})
+	store.EXPECT().VoidTransferTx(gomock.Any(), gomock.Eq(int64(11))).Times(0)
+
+	expired, err := NewHoldExpirer(store, time.Second, nil).ExpireHolds(ctx, time.Now())
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, expired)
+}
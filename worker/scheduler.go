@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/NoahFola/simple_bank/util"
+)
+
+const (
+	defaultSchedulerInterval  = time.Minute
+	defaultSchedulerBatchSize = 100
+)
+
+// Scheduler runs scheduled transfers as they fall due. Several schedulers
+// may share a database; each claims different rows.
+type Scheduler struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int
+	clock     func() time.Time
+	logger    *slog.Logger
+}
+
+// NewScheduler creates a Scheduler that looks for due transfers every
+// interval, defaulting to once a minute. clock tells it what time it is and
+// defaults to time.Now. Outcomes are logged to logger; a nil logger
+// discards them.
+func NewScheduler(store db.Store, interval time.Duration, clock func() time.Time, logger *slog.Logger) *Scheduler {
+	if interval <= 0 {
+		interval = defaultSchedulerInterval
+	}
+	if clock == nil {
+		clock = time.Now
+	}
+	if logger == nil {
+		logger = util.DiscardLogger()
+	}
+
+	return &Scheduler{
+		store:     store,
+		interval:  interval,
+		batchSize: defaultSchedulerBatchSize,
+		clock:     clock,
+		logger:    logger.With("worker", "scheduler"),
+	}
+}
+
+// Run runs due transfers until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runs, err := s.RunDue(ctx)
+			if err != nil {
+				s.logger.ErrorContext(ctx, "cannot run scheduled transfers", "error", err)
+			}
+			if runs > 0 {
+				s.logger.InfoContext(ctx, "ran scheduled transfers", "runs", runs)
+			}
+		}
+	}
+}
+
+// RunDue runs up to one batch of the transfers due now, returning how many
+// were run. Transfers the order rules out count as run; their outcome is
+// recorded with the scheduled transfer. Any other error stops the batch and
+// leaves the transfer due.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	now := s.clock()
+
+	runs := 0
+	for runs < s.batchSize {
+		result, err := s.store.RunScheduledTransferTx(ctx, now)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return runs, nil
+			}
+			return runs, err
+		}
+		runs++
+
+		if result.Run.Status == db.ScheduledRunStatusFailed {
+			s.logger.WarnContext(ctx, "scheduled transfer failed",
+				"scheduled_transfer_id", result.ScheduledTransfer.ID,
+				"run_id", result.Run.ID,
+				"error", result.Run.Error)
+		}
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/NoahFola/simple_bank/db/mock"
+	db "github.com/NoahFola/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerRunDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	gomock.InOrder(
+		store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+			Times(1).Return(db.RunScheduledTransferTxResult{
+			Run: db.ScheduledTransferRun{ScheduledTransferID: 1, Status: db.ScheduledRunStatusSucceeded},
+		}, nil),
+		store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+			Times(1).Return(db.RunScheduledTransferTxResult{
+			Run: db.ScheduledTransferRun{ScheduledTransferID: 2, Status: db.ScheduledRunStatusFailed, Error: "insufficient funds"},
+		}, nil),
+		store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+			Times(1).Return(db.RunScheduledTransferTxResult{}, sql.ErrNoRows),
+	)
+
+	runs, err := NewScheduler(store, time.Minute, clock, nil).RunDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, runs)
+}
+
+func TestSchedulerRunDueBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	scheduler := NewScheduler(store, time.Minute, func() time.Time { return now }, nil)
+	scheduler.batchSize = 3
+
+	// stops after a batch even if more are due
+	store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+		Times(3).Return(db.RunScheduledTransferTxResult{}, nil)
+
+	runs, err := scheduler.RunDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, runs)
+}
+
+func TestSchedulerRunDueError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+		Times(1).Return(db.RunScheduledTransferTxResult{}, sql.ErrConnDone)
+
+	runs, err := NewScheduler(store, 0, nil, nil).RunDue(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Zero(t, runs)
+}